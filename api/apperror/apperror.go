@@ -0,0 +1,146 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindBadRequest
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindTooManyRequests
+)
+
+// Status is the HTTP status a kind of error is reported with.
+func (k Kind) Status() int {
+	switch k {
+	case KindBadRequest:
+		return http.StatusBadRequest
+	case KindValidation:
+		return http.StatusUnprocessableEntity
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindTooManyRequests:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// FieldError is one problem with one input field. Field is the path of the field in
+// the request body, e.g. "email" or "author.id".
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error with a stable, machine-readable Code. Message is meant for
+// people and may change; clients should branch on Code.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns a copy of e caused by err, so the cause can be logged without being shown.
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func BadRequest(code, message string) *Error {
+	return New(KindBadRequest, code, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return New(KindUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return New(KindForbidden, code, message)
+}
+
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+func TooManyRequests(code, message string) *Error {
+	return New(KindTooManyRequests, code, message)
+}
+
+// Invalid reports a single invalid field.
+func Invalid(field, code, message string) *Error {
+	return Validation(FieldError{Field: field, Code: code, Message: message})
+}
+
+// Validation reports every invalid field of a request at once.
+func Validation(fields ...FieldError) *Error {
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Message
+	}
+	message := strings.Join(messages, "; ")
+	if message == "" {
+		message = "Validation Failed"
+	}
+
+	return &Error{Kind: KindValidation, Code: "validation_failed", Message: message, Fields: fields}
+}
+
+var errInternal = New(KindInternal, "internal_error", "Internal Server Error")
+
+// From classifies err: domain errors are returned as they are, missing records become
+// not found and Postgres errors are mapped by SQLSTATE and constraint. Anything else is
+// an internal error whose message is not shown to clients.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	if gorm.IsRecordNotFoundError(err) {
+		return &Error{Kind: KindNotFound, Code: "not_found", Message: "Not Found", Err: err}
+	}
+
+	if pgErr := fromPostgres(err); pgErr != nil {
+		return pgErr
+	}
+
+	return errInternal.Wrap(err)
+}
@@ -0,0 +1,73 @@
+package apperror
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+// constraint describes how a violated constraint is reported to clients.
+type constraint struct {
+	field   string
+	code    string
+	message string
+}
+
+// constraints maps Postgres constraint names to errors. Unique constraints created
+// by a column's UNIQUE keyword are named <table>_<column>_key.
+var constraints = map[string]constraint{
+	"users_email_key":     {field: "email", code: "email_taken", message: "Email already taken"},
+	"posts_title_key":     {field: "title", code: "title_taken", message: "Title already taken"},
+	"posts_slug_key":      {field: "title", code: "slug_taken", message: "A post with a similar title already exists"},
+	"post_slugs_slug_key": {field: "title", code: "slug_taken", message: "A post with a similar title already exists"},
+	"tags_slug_key":       {field: "name", code: "tag_taken", message: "Tag already exists"},
+	"categories_slug_key": {field: "name", code: "category_taken", message: "A category with a similar name already exists"},
+}
+
+// fromPostgres classifies driver errors by SQLSTATE, or returns nil for other errors.
+func fromPostgres(err error) *Error {
+	var pgErr *pq.Error
+	if !errors.As(err, &pgErr) {
+		if errs, ok := err.(gorm.Errors); ok {
+			for _, e := range errs.GetErrors() {
+				if classified := fromPostgres(e); classified != nil {
+					return classified
+				}
+			}
+		}
+		return nil
+	}
+
+	switch pgErr.Code.Name() {
+	case "unique_violation":
+		if c, ok := constraints[pgErr.Constraint]; ok {
+			return &Error{
+				Kind:    KindConflict,
+				Code:    c.code,
+				Message: c.message,
+				Fields:  []FieldError{{Field: c.field, Code: c.code, Message: c.message}},
+				Err:     err,
+			}
+		}
+		return &Error{Kind: KindConflict, Code: "conflict", Message: "Resource already exists", Err: err}
+	case "foreign_key_violation":
+		return &Error{Kind: KindConflict, Code: "reference_violation", Message: "Referenced resource does not exist or is still in use", Err: err}
+	case "not_null_violation":
+		return &Error{
+			Kind:    KindValidation,
+			Code:    "validation_failed",
+			Message: "Value Required",
+			Fields:  []FieldError{{Field: pgErr.Column, Code: "required", Message: "Value Required"}},
+			Err:     err,
+		}
+	case "string_data_right_truncation":
+		return &Error{Kind: KindValidation, Code: "value_too_long", Message: "Value too long", Err: err}
+	case "check_violation":
+		return &Error{Kind: KindValidation, Code: "validation_failed", Message: "Value not allowed", Err: err}
+	case "serialization_failure", "deadlock_detected":
+		return &Error{Kind: KindConflict, Code: "concurrent_update", Message: "The resource was changed concurrently, retry the request", Err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"github.com/stylll/GoBlog/api/apperror"
+	"github.com/stylll/GoBlog/api/models"
+)
+
+type Permission string
+
+const (
+	ManageUsers      Permission = "users:manage"
+	ReadAnyPost      Permission = "posts:read_any"
+	EditAnyPost      Permission = "posts:edit_any"
+	UnpublishAnyPost Permission = "posts:unpublish_any"
+	DeleteAnyPost    Permission = "posts:delete_any"
+	ManageTaxonomy   Permission = "taxonomy:manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	models.RoleAdmin:  {ManageUsers, ReadAnyPost, EditAnyPost, UnpublishAnyPost, DeleteAnyPost, ManageTaxonomy},
+	models.RoleEditor: {ReadAnyPost, EditAnyPost, UnpublishAnyPost, ManageTaxonomy},
+	models.RoleAuthor: {},
+}
+
+func HasPermission(role string, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+var ErrForbidden = apperror.Forbidden("forbidden", "Forbidden")
+
+// Authorize allows the caller when their role grants permission or when they own the
+// resource. ownerID is 0 for resources nobody owns, such as another user's role.
+func Authorize(p *Principal, permission Permission, ownerID int) error {
+	if p.Can(permission) {
+		return nil
+	}
+
+	if ownerID != 0 && p.UserID == int64(ownerID) {
+		return nil
+	}
+
+	return ErrForbidden
+}
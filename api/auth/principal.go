@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// Principal is the caller of an authenticated request, as read from the access token.
+type Principal struct {
+	UserID    int64
+	SessionID int64
+	Role      string
+	ExpiresAt time.Time
+}
+
+// Can reports whether the principal's role grants permission.
+func (p *Principal) Can(permission Permission) bool {
+	return HasPermission(p.Role, permission)
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal stored by the authentication middleware, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// UserID returns the ID of the signed-in caller, or 0 for anonymous requests.
+func UserID(ctx context.Context) int64 {
+	if p, ok := FromContext(ctx); ok {
+		return p.UserID
+	}
+
+	return 0
+}
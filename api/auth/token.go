@@ -1,25 +1,53 @@
 package auth
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
-	"os"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/stylll/GoBlog/api/config"
 )
 
-func CreateToken(userId int) (string, error) {
+var (
+	secret               []byte
+	AccessTokenLifetime  = time.Hour * 1
+	RefreshTokenLifetime = time.Hour * 24 * 30
+)
+
+// Configure sets the signing secret and token lifetimes. Zero lifetimes keep the defaults.
+func Configure(cfg config.AuthConfig) {
+	secret = []byte(cfg.Secret)
+	if cfg.AccessTokenLifetime > 0 {
+		AccessTokenLifetime = cfg.AccessTokenLifetime
+	}
+	if cfg.RefreshTokenLifetime > 0 {
+		RefreshTokenLifetime = cfg.RefreshTokenLifetime
+	}
+}
+
+type TokenDetails struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+func CreateToken(userId, sessionId int, role string) (string, error) {
+	if len(secret) == 0 {
+		return "", errors.New("auth: signing secret is not configured")
+	}
+
 	claims := jwt.MapClaims{}
 	claims["authorized"] = true
 	claims["userId"] = userId
-	claims["exp"] = time.Now().Add(time.Hour * 1).Unix() // expires after 1 hour
+	claims["sessionId"] = sessionId
+	claims["role"] = role
+	claims["exp"] = time.Now().Add(AccessTokenLifetime).Unix()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("API_SECRET")))
+	return token.SignedString(secret)
 }
 
 func JWTParseCallback(token *jwt.Token) (interface{}, error) {
@@ -27,20 +55,58 @@ func JWTParseCallback(token *jwt.Token) (interface{}, error) {
 		return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
 	}
 
-	return []byte(os.Getenv("API_SECRET")), nil
+	if len(secret) == 0 {
+		return nil, errors.New("auth: signing secret is not configured")
+	}
+
+	return secret, nil
 }
 
-func TokenValid(r *http.Request) error {
-	tokenString := ExtractToken(r)
+var (
+	errInvalidToken  = errors.New("auth: invalid token")
+	errInvalidClaims = errors.New("auth: token is missing claims")
+)
+
+// ParseToken verifies an access token and reads its claims into a principal.
+func ParseToken(tokenString string) (*Principal, error) {
 	token, err := jwt.Parse(tokenString, JWTParseCallback)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		Pretty(claims)
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errInvalidToken
 	}
 
-	return nil
+	userID, ok := intClaim(claims, "userId")
+	if !ok || userID == 0 {
+		return nil, errInvalidClaims
+	}
+	sessionID, ok := intClaim(claims, "sessionId")
+	if !ok {
+		return nil, errInvalidClaims
+	}
+	role, ok := claims["role"].(string)
+	if !ok {
+		return nil, errInvalidClaims
+	}
+	exp, ok := intClaim(claims, "exp")
+	if !ok {
+		return nil, errInvalidClaims
+	}
+
+	return &Principal{
+		UserID:    userID,
+		SessionID: sessionID,
+		Role:      role,
+		ExpiresAt: time.Unix(exp, 0),
+	}, nil
+}
+
+// IsClaimsError reports whether ParseToken failed on a well-signed token with missing claims.
+func IsClaimsError(err error) bool {
+	return err == errInvalidClaims
 }
 
 func ExtractToken(r *http.Request) string {
@@ -58,30 +124,12 @@ func ExtractToken(r *http.Request) string {
 	return ""
 }
 
-func Pretty(data interface{}) {
-	b, err := json.MarshalIndent(data, "", " ")
-	if err != nil {
-		log.Println(err)
-		return
-	}
-
-	fmt.Println(string(b))
-}
-
-func ExtractTokenID(r *http.Request) (int64, error) {
-	tokenString := ExtractToken(r)
-	token, err := jwt.Parse(tokenString, JWTParseCallback)
-	if err != nil {
-		return 0, err
-	}
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		uid, err := strconv.ParseInt(fmt.Sprintf("%.0f", claims["userId"]), 10, 64)
-		if err != nil {
-			return 0, err
-		}
-
-		return uid, nil
+// intClaim reads a numeric claim, which JSON decodes as a float64.
+func intClaim(claims jwt.MapClaims, name string) (int64, bool) {
+	value, ok := claims[name].(float64)
+	if !ok {
+		return 0, false
 	}
 
-	return 0, nil
+	return int64(value), true
 }
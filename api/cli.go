@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/stylll/GoBlog/api/config"
+)
+
+const usage = `Usage: goblog <command> [arguments]
+
+Commands:
+  serve                                   start the HTTP server
+  migrate up [version]                    apply pending migrations
+  migrate down [steps]                    revert applied migrations
+  migrate status                          list applied and pending migrations
+  seed                                    insert the sample authors and posts
+  user create -email -firstname -lastname -password [-role] [-verified]
+  user promote -email -role               change a user's role
+  user disable -email                     block sign-in and revoke sessions
+  post export [-out file]                 write all posts as JSON
+  post import [-in file]                  create posts from exported JSON
+  token issue -email                      open a session and print its tokens
+
+Every command also accepts the configuration flags, e.g. -config goblog.yaml,
+-env-file .env, -addr :8080 or -db-host localhost. Run a command with -h to
+list them. Settings are read from defaults, the .env file (only for variables
+the environment leaves unset), environment variables, the config file and
+flags, each overriding the one before.
+`
+
+// Execute runs the subcommand named by args[0]. Running without a command starts the server.
+func Execute(args []string) error {
+	if len(args) == 0 {
+		return serve(nil)
+	}
+
+	switch args[0] {
+	case "serve":
+		return serve(args[1:])
+	case "migrate":
+		return migrate(args[1:])
+	case "seed":
+		return seedDatabase(args[1:])
+	case "user":
+		return userCommand(args[1:])
+	case "post":
+		return postCommand(args[1:])
+	case "token":
+		return tokenCommand(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// subcommand picks the nested command name, e.g. "create" in "user create".
+func subcommand(group string, args []string) (string, []string, error) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return "", nil, fmt.Errorf("%s needs a subcommand", group)
+	}
+
+	return args[0], args[1:], nil
+}
+
+// newFlagSet creates the flag set for a command with the configuration flags registered on it.
+func newFlagSet(name string) (*flag.FlagSet, *config.Loader) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	return flags, config.NewLoader(flags)
+}
+
+// required checks that each named flag was given a value.
+func required(flags *flag.FlagSet, names ...string) error {
+	for _, name := range names {
+		if flags.Lookup(name).Value.String() == "" {
+			return errors.New("-" + name + " is required")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/stylll/GoBlog/api/ratelimit"
+)
+
+type Config struct {
+	Addr                 string        `env:"HTTP_ADDR" yaml:"addr" flag:"addr" usage:"address to listen on"`
+	MetricsAddr          string        `env:"METRICS_ADDR" yaml:"metrics_addr" flag:"metrics-addr" usage:"separate address serving /metrics, e.g. 127.0.0.1:9090; empty disables metrics"`
+	AppURL               string        `env:"APP_URL" yaml:"app_url" flag:"app-url" usage:"public base URL used in emailed links"`
+	PasswordResetURL     string        `env:"PASSWORD_RESET_URL" yaml:"password_reset_url" flag:"password-reset-url" usage:"frontend page that takes ?token= and posts the new password to /password/reset"`
+	RequireVerifiedEmail bool          `env:"REQUIRE_VERIFIED_EMAIL" yaml:"require_verified_email" flag:"require-verified-email" usage:"only verified users may create posts"`
+	PublishInterval      time.Duration `env:"POST_PUBLISH_INTERVAL" yaml:"publish_interval" flag:"publish-interval" usage:"how often scheduled posts are published, 0 to disable"`
+
+	HTTP      HTTPConfig      `yaml:"http"`
+	Log       LogConfig       `yaml:"log"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	CORS      CORSConfig      `yaml:"cors"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Mail      MailConfig      `yaml:"mail"`
+}
+
+type HTTPConfig struct {
+	ReadTimeout     time.Duration `env:"HTTP_READ_TIMEOUT" yaml:"read_timeout" flag:"read-timeout" usage:"maximum time to read a request, body included"`
+	WriteTimeout    time.Duration `env:"HTTP_WRITE_TIMEOUT" yaml:"write_timeout" flag:"write-timeout" usage:"maximum time to write a response"`
+	IdleTimeout     time.Duration `env:"HTTP_IDLE_TIMEOUT" yaml:"idle_timeout" flag:"idle-timeout" usage:"how long keep-alive connections may sit idle"`
+	ShutdownTimeout time.Duration `env:"HTTP_SHUTDOWN_TIMEOUT" yaml:"shutdown_timeout" flag:"shutdown-timeout" usage:"how long to drain connections on shutdown"`
+	TLSCertFile     string        `env:"TLS_CERT_FILE" yaml:"tls_cert_file" flag:"tls-cert" usage:"certificate file; serves HTTPS together with -tls-key"`
+	TLSKeyFile      string        `env:"TLS_KEY_FILE" yaml:"tls_key_file" flag:"tls-key" usage:"private key file for -tls-cert"`
+}
+
+// TLS reports whether the server should serve HTTPS.
+func (cfg HTTPConfig) TLS() bool {
+	return cfg.TLSCertFile != "" && cfg.TLSKeyFile != ""
+}
+
+type LogConfig struct {
+	Level              string        `env:"LOG_LEVEL" yaml:"level" flag:"log-level" usage:"debug, info, warn or error"`
+	Format             string        `env:"LOG_FORMAT" yaml:"format" flag:"log-format" usage:"json or logfmt"`
+	SQLLevel           string        `env:"LOG_SQL_LEVEL" yaml:"sql_level" flag:"log-sql-level" usage:"debug logs every query, warn only slow ones, error only failures, off none"`
+	SlowQueryThreshold time.Duration `env:"LOG_SLOW_QUERY" yaml:"slow_query" flag:"log-slow-query" usage:"queries slower than this are logged at warn"`
+}
+
+type RateLimitConfig struct {
+	Enabled          bool          `env:"RATE_LIMIT_ENABLED" yaml:"enabled" flag:"rate-limit" usage:"throttle logins, sign-ups and writes"`
+	TrustProxy       bool          `env:"RATE_LIMIT_TRUST_PROXY" yaml:"trust_proxy" flag:"rate-limit-trust-proxy" usage:"key clients by X-Forwarded-For"`
+	Login            string        `env:"RATE_LIMIT_LOGIN" yaml:"login" flag:"rate-limit-login" usage:"login, token refresh and password reset requests per IP, e.g. 10/1m"`
+	Signup           string        `env:"RATE_LIMIT_SIGNUP" yaml:"signup" flag:"rate-limit-signup" usage:"sign-ups per IP"`
+	Writes           string        `env:"RATE_LIMIT_WRITES" yaml:"writes" flag:"rate-limit-writes" usage:"post writes per user"`
+	LockoutThreshold int           `env:"LOGIN_LOCKOUT_THRESHOLD" yaml:"lockout_threshold" flag:"login-lockout-threshold" usage:"failed logins before an account is locked"`
+	LockoutBase      time.Duration `env:"LOGIN_LOCKOUT_BASE" yaml:"lockout_base" flag:"login-lockout-base" usage:"first lockout, doubled on every further failure"`
+	LockoutMax       time.Duration `env:"LOGIN_LOCKOUT_MAX" yaml:"lockout_max" flag:"login-lockout-max" usage:"longest lockout"`
+}
+
+// CORSConfig lists what cross-origin browsers may do. CORS is off while AllowedOrigins is empty.
+type CORSConfig struct {
+	AllowedOrigins   []string      `env:"CORS_ALLOWED_ORIGINS" yaml:"allowed_origins" flag:"cors-origins" usage:"comma-separated origins, or * for any"`
+	AllowedMethods   []string      `env:"CORS_ALLOWED_METHODS" yaml:"allowed_methods" flag:"cors-methods" usage:"comma-separated methods allowed cross-origin"`
+	AllowedHeaders   []string      `env:"CORS_ALLOWED_HEADERS" yaml:"allowed_headers" flag:"cors-headers" usage:"comma-separated request headers allowed cross-origin"`
+	ExposedHeaders   []string      `env:"CORS_EXPOSED_HEADERS" yaml:"exposed_headers" flag:"cors-exposed-headers" usage:"comma-separated response headers scripts may read"`
+	AllowCredentials bool          `env:"CORS_ALLOW_CREDENTIALS" yaml:"allow_credentials" flag:"cors-credentials" usage:"allow cookies and Authorization headers cross-origin"`
+	MaxAge           time.Duration `env:"CORS_MAX_AGE" yaml:"max_age" flag:"cors-max-age" usage:"how long browsers may cache a preflight"`
+}
+
+type AuthConfig struct {
+	Secret               string        `env:"API_SECRET" yaml:"secret" flag:"api-secret" usage:"key used to sign access tokens"`
+	AccessTokenLifetime  time.Duration `env:"ACCESS_TOKEN_LIFETIME" yaml:"access_token_lifetime" flag:"access-token-lifetime" usage:"how long access tokens stay valid"`
+	RefreshTokenLifetime time.Duration `env:"REFRESH_TOKEN_LIFETIME" yaml:"refresh_token_lifetime" flag:"refresh-token-lifetime" usage:"how long refresh tokens stay valid"`
+}
+
+type DatabaseConfig struct {
+	Host     string `env:"DB_HOST" yaml:"host" flag:"db-host" usage:"database host"`
+	Port     string `env:"DB_PORT" yaml:"port" flag:"db-port" usage:"database port"`
+	User     string `env:"DB_USER" yaml:"user" flag:"db-user" usage:"database user"`
+	Password string `env:"DB_PASSWORD" yaml:"password" flag:"db-password" usage:"database password"`
+	Name     string `env:"DB_NAME" yaml:"name" flag:"db-name" usage:"database name"`
+	SSLMode  string `env:"DB_SSLMODE" yaml:"sslmode" flag:"db-sslmode" usage:"postgres sslmode"`
+}
+
+type MailConfig struct {
+	Driver       string `env:"MAIL_DRIVER" yaml:"driver" flag:"mail-driver" usage:"smtp, file or memory"`
+	From         string `env:"MAIL_FROM" yaml:"from" flag:"mail-from" usage:"sender address"`
+	OutboxDir    string `env:"MAIL_OUTBOX_DIR" yaml:"outbox_dir" flag:"mail-outbox-dir" usage:"directory the file driver writes to"`
+	SMTPHost     string `env:"SMTP_HOST" yaml:"smtp_host" flag:"smtp-host" usage:"SMTP server host"`
+	SMTPPort     string `env:"SMTP_PORT" yaml:"smtp_port" flag:"smtp-port" usage:"SMTP server port"`
+	SMTPUsername string `env:"SMTP_USERNAME" yaml:"smtp_username" flag:"smtp-username" usage:"SMTP user name"`
+	SMTPPassword string `env:"SMTP_PASSWORD" yaml:"smtp_password" flag:"smtp-password" usage:"SMTP password"`
+}
+
+func Defaults() *Config {
+	return &Config{
+		Addr:             ":8080",
+		AppURL:           "http://localhost:8080",
+		PasswordResetURL: "http://localhost:3000/reset-password",
+		PublishInterval:  time.Minute,
+		HTTP: HTTPConfig{
+			ReadTimeout:     time.Second * 15,
+			WriteTimeout:    time.Second * 30,
+			IdleTimeout:     time.Second * 120,
+			ShutdownTimeout: time.Second * 20,
+		},
+		Log: LogConfig{
+			Level:              "info",
+			Format:             "json",
+			SQLLevel:           "warn",
+			SlowQueryThreshold: time.Millisecond * 200,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:          true,
+			Login:            "10/1m",
+			Signup:           "5/1h",
+			Writes:           "60/1m",
+			LockoutThreshold: 5,
+			LockoutBase:      time.Minute,
+			LockoutMax:       time.Hour,
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "X-Request-ID"},
+			ExposedHeaders: []string{"Location", "Retry-After", "X-Request-ID", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
+			MaxAge:         time.Minute * 10,
+		},
+		Auth: AuthConfig{
+			AccessTokenLifetime:  time.Hour,
+			RefreshTokenLifetime: time.Hour * 24 * 30,
+		},
+		Database: DatabaseConfig{
+			Port:    "5432",
+			SSLMode: "disable",
+		},
+		Mail: MailConfig{
+			Driver:    "file",
+			From:      "no-reply@goblog.local",
+			OutboxDir: "outbox",
+			SMTPPort:  "587",
+		},
+	}
+}
+
+func (cfg *Config) DatabaseURL() string {
+	db := cfg.Database
+	return fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=%s password=%s",
+		db.Host, db.Port, db.User, db.Name, db.SSLMode, db.Password)
+}
+
+// Validate reports every missing or malformed setting at once.
+func (cfg *Config) Validate() error {
+	problems := []string{}
+	require := func(value, name string) {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, name+" is required")
+		}
+	}
+
+	require(cfg.Addr, "HTTP_ADDR")
+	require(cfg.Auth.Secret, "API_SECRET")
+	require(cfg.Database.Host, "DB_HOST")
+	require(cfg.Database.Port, "DB_PORT")
+	require(cfg.Database.User, "DB_USER")
+	require(cfg.Database.Name, "DB_NAME")
+
+	if cfg.MetricsAddr != "" && cfg.MetricsAddr == cfg.Addr {
+		problems = append(problems, "METRICS_ADDR must differ from HTTP_ADDR")
+	}
+	if u, err := url.Parse(cfg.AppURL); err != nil || u.Scheme == "" || u.Host == "" {
+		problems = append(problems, "APP_URL must be an absolute URL")
+	}
+	if u, err := url.Parse(cfg.PasswordResetURL); err != nil || u.Scheme == "" || u.Host == "" {
+		problems = append(problems, "PASSWORD_RESET_URL must be an absolute URL")
+	}
+	if cfg.PublishInterval < 0 {
+		problems = append(problems, "POST_PUBLISH_INTERVAL must not be negative")
+	}
+	if cfg.HTTP.ReadTimeout <= 0 || cfg.HTTP.WriteTimeout <= 0 || cfg.HTTP.IdleTimeout <= 0 {
+		problems = append(problems, "HTTP_READ_TIMEOUT, HTTP_WRITE_TIMEOUT and HTTP_IDLE_TIMEOUT must be positive")
+	}
+	if cfg.HTTP.ShutdownTimeout <= 0 {
+		problems = append(problems, "HTTP_SHUTDOWN_TIMEOUT must be positive")
+	}
+	if (cfg.HTTP.TLSCertFile == "") != (cfg.HTTP.TLSKeyFile == "") {
+		problems = append(problems, "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if !oneOf(cfg.Log.Level, "debug", "info", "warn", "error") {
+		problems = append(problems, "LOG_LEVEL must be debug, info, warn or error")
+	}
+	if !oneOf(cfg.Log.Format, "json", "logfmt") {
+		problems = append(problems, "LOG_FORMAT must be json or logfmt")
+	}
+	if !oneOf(cfg.Log.SQLLevel, "debug", "warn", "error", "off") {
+		problems = append(problems, "LOG_SQL_LEVEL must be debug, warn, error or off")
+	}
+	if cfg.Log.SlowQueryThreshold < 0 {
+		problems = append(problems, "LOG_SLOW_QUERY must not be negative")
+	}
+	for _, limit := range []string{cfg.RateLimit.Login, cfg.RateLimit.Signup, cfg.RateLimit.Writes} {
+		if _, err := ratelimit.ParseLimit(limit); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if cfg.RateLimit.LockoutThreshold < 1 {
+		problems = append(problems, "LOGIN_LOCKOUT_THRESHOLD must be at least 1")
+	}
+	if cfg.RateLimit.LockoutBase <= 0 || cfg.RateLimit.LockoutMax < cfg.RateLimit.LockoutBase {
+		problems = append(problems, "LOGIN_LOCKOUT_BASE must be positive and at most LOGIN_LOCKOUT_MAX")
+	}
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		if origin == "*" {
+			if cfg.CORS.AllowCredentials {
+				problems = append(problems, "CORS_ALLOW_CREDENTIALS cannot be combined with CORS_ALLOWED_ORIGINS=*")
+			}
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			problems = append(problems, fmt.Sprintf("CORS origin %q must look like https://app.example.com", origin))
+		}
+	}
+	if cfg.CORS.MaxAge < 0 {
+		problems = append(problems, "CORS_MAX_AGE must not be negative")
+	}
+	if cfg.Auth.AccessTokenLifetime <= 0 {
+		problems = append(problems, "ACCESS_TOKEN_LIFETIME must be positive")
+	}
+	if cfg.Auth.RefreshTokenLifetime <= 0 {
+		problems = append(problems, "REFRESH_TOKEN_LIFETIME must be positive")
+	}
+
+	switch cfg.Mail.Driver {
+	case "smtp":
+		require(cfg.Mail.SMTPHost, "SMTP_HOST")
+		require(cfg.Mail.SMTPPort, "SMTP_PORT")
+	case "file":
+		require(cfg.Mail.OutboxDir, "MAIL_OUTBOX_DIR")
+	case "memory":
+	default:
+		problems = append(problems, "MAIL_DRIVER must be smtp, file or memory")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v2"
+)
+
+const defaultEnvFile = ".env"
+
+// Loader reads configuration from, in increasing order of precedence:
+// defaults, the .env file, environment variables, a YAML config file and
+// command-line flags. Like godotenv.Load, the .env file only fills in keys
+// the environment does not set, so it never overrides a deployment.
+type Loader struct {
+	flags      *flag.FlagSet
+	configFile *string
+	envFile    *string
+	values     map[string]*string
+	lookupEnv  func(string) (string, bool)
+}
+
+// NewLoader registers -config, -env-file and one flag per setting on flags.
+// Call Load after flags has been parsed.
+func NewLoader(flags *flag.FlagSet) *Loader {
+	l := &Loader{
+		flags:      flags,
+		configFile: flags.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML config file"),
+		envFile:    flags.String("env-file", defaultEnvFile, "path to a .env file"),
+		values:     map[string]*string{},
+		lookupEnv:  os.LookupEnv,
+	}
+
+	walk(reflect.ValueOf(Defaults()).Elem(), func(field reflect.StructField, _ reflect.Value) {
+		if name := field.Tag.Get("flag"); name != "" {
+			l.values[name] = flags.String(name, "", field.Tag.Get("usage"))
+		}
+	})
+
+	return l
+}
+
+func (l *Loader) Load() (*Config, error) {
+	cfg := Defaults()
+	root := reflect.ValueOf(cfg).Elem()
+
+	dotenv, err := godotenv.Read(*l.envFile)
+	if err != nil && !(os.IsNotExist(err) && *l.envFile == defaultEnvFile) {
+		return nil, fmt.Errorf("cannot read %s: %v", *l.envFile, err)
+	}
+	err = applyEnv(root, func(key string) (string, bool) {
+		if _, set := l.lookupEnv(key); set {
+			return "", false
+		}
+		value, ok := dotenv[key]
+		return value, ok
+	}, *l.envFile)
+	if err != nil {
+		return nil, err
+	}
+
+	err = applyEnv(root, l.lookupEnv, "environment")
+	if err != nil {
+		return nil, err
+	}
+
+	if *l.configFile != "" {
+		err = loadFile(cfg, *l.configFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	set := map[string]bool{}
+	l.flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	walk(root, func(field reflect.StructField, value reflect.Value) {
+		name := field.Tag.Get("flag")
+		if err == nil && set[name] {
+			err = assign(value, *l.values[name])
+			if err != nil {
+				err = fmt.Errorf("flag -%s: %v", name, err)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+	default:
+		return fmt.Errorf("unsupported config file %s: only YAML is supported", path)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	err = yaml.UnmarshalStrict(data, cfg)
+	if err != nil {
+		return fmt.Errorf("cannot parse %s: %v", path, err)
+	}
+
+	return nil
+}
+
+func applyEnv(root reflect.Value, lookup func(string) (string, bool), source string) error {
+	var err error
+	walk(root, func(field reflect.StructField, value reflect.Value) {
+		key := field.Tag.Get("env")
+		if err != nil || key == "" {
+			return
+		}
+
+		if raw, ok := lookup(key); ok && raw != "" {
+			err = assign(value, raw)
+			if err != nil {
+				err = fmt.Errorf("%s %s: %v", source, key, err)
+			}
+		}
+	})
+
+	return err
+}
+
+// walk calls fn for every leaf setting, descending into nested sections.
+func walk(v reflect.Value, fn func(reflect.StructField, reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			walk(value, fn)
+			continue
+		}
+
+		fn(field, value)
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func assign(v reflect.Value, raw string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	default:
+		v.SetString(raw)
+	}
+
+	return nil
+}
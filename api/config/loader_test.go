@@ -0,0 +1,197 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+const requiredEnv = "API_SECRET=from-dotenv\nDB_HOST=dotenv-host\nDB_USER=stephen\nDB_NAME=goblog\n"
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	err := ioutil.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goblog-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	envFile := writeFile(t, dir, ".env", requiredEnv+"HTTP_ADDR=:7000\nLOG_LEVEL=debug\n")
+	configFile := writeFile(t, dir, "goblog.yaml", "log:\n  level: warn\n")
+
+	samples := []struct {
+		name   string
+		env    map[string]string
+		args   []string
+		secret string
+		host   string
+		addr   string
+		level  string
+	}{
+		{
+			name:   "dotenv fills in unset variables",
+			secret: "from-dotenv",
+			host:   "dotenv-host",
+			addr:   ":7000",
+			level:  "debug",
+		},
+		{
+			name:   "environment overrides dotenv",
+			env:    map[string]string{"API_SECRET": "from-env", "HTTP_ADDR": ":9000"},
+			secret: "from-env",
+			host:   "dotenv-host",
+			addr:   ":9000",
+			level:  "debug",
+		},
+		{
+			name:   "empty environment variable still hides dotenv",
+			env:    map[string]string{"HTTP_ADDR": ""},
+			secret: "from-dotenv",
+			host:   "dotenv-host",
+			addr:   ":8080",
+			level:  "debug",
+		},
+		{
+			name:   "yaml overrides environment",
+			env:    map[string]string{"LOG_LEVEL": "error"},
+			args:   []string{"-config", configFile},
+			secret: "from-dotenv",
+			host:   "dotenv-host",
+			addr:   ":7000",
+			level:  "warn",
+		},
+		{
+			name:   "flags override everything",
+			env:    map[string]string{"API_SECRET": "from-env", "DB_HOST": "env-host"},
+			args:   []string{"-config", configFile, "-api-secret", "from-flag", "-log-level", "info"},
+			secret: "from-flag",
+			host:   "env-host",
+			addr:   ":7000",
+			level:  "info",
+		},
+	}
+
+	for _, sample := range samples {
+		flags := flag.NewFlagSet("goblog", flag.ContinueOnError)
+		loader := NewLoader(flags)
+		loader.lookupEnv = func(key string) (string, bool) {
+			value, ok := sample.env[key]
+			return value, ok
+		}
+
+		err := flags.Parse(append([]string{"-env-file", envFile}, sample.args...))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, err := loader.Load()
+		if err != nil {
+			t.Fatalf("%s: %v", sample.name, err)
+		}
+		assert.Equal(t, cfg.Auth.Secret, sample.secret)
+		assert.Equal(t, cfg.Database.Host, sample.host)
+		assert.Equal(t, cfg.Addr, sample.addr)
+		assert.Equal(t, cfg.Log.Level, sample.level)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Config {
+		cfg := Defaults()
+		cfg.Auth.Secret = "secret"
+		cfg.Database.Host = "127.0.0.1"
+		cfg.Database.User = "stephen"
+		cfg.Database.Name = "goblog"
+		return cfg
+	}
+
+	samples := []struct {
+		name    string
+		change  func(*Config)
+		problem string
+	}{
+		{"empty secret", func(cfg *Config) { cfg.Auth.Secret = "" }, "API_SECRET is required"},
+		{"blank secret", func(cfg *Config) { cfg.Auth.Secret = "  " }, "API_SECRET is required"},
+		{"missing database", func(cfg *Config) { cfg.Database.Host = "" }, "DB_HOST is required"},
+		{"relative app url", func(cfg *Config) { cfg.AppURL = "/blog" }, "APP_URL must be an absolute URL"},
+		{"bad log level", func(cfg *Config) { cfg.Log.Level = "trace" }, "LOG_LEVEL must be debug, info, warn or error"},
+		{"half of tls", func(cfg *Config) { cfg.HTTP.TLSCertFile = "tls.crt" }, "TLS_CERT_FILE and TLS_KEY_FILE must be set together"},
+		{"unknown mail driver", func(cfg *Config) { cfg.Mail.Driver = "pigeon" }, "MAIL_DRIVER must be smtp, file or memory"},
+		{"smtp without host", func(cfg *Config) { cfg.Mail.Driver = "smtp" }, "SMTP_HOST is required"},
+	}
+
+	assert.Equal(t, valid().Validate(), nil)
+
+	for _, sample := range samples {
+		cfg := valid()
+		sample.change(cfg)
+
+		err := cfg.Validate()
+		if err == nil || !strings.Contains(err.Error(), sample.problem) {
+			t.Errorf("%s: got %v, want %q", sample.name, err, sample.problem)
+		}
+	}
+
+	cfg := valid()
+	cfg.Auth.Secret = ""
+	cfg.Database.Name = ""
+	err := cfg.Validate()
+	assert.Equal(t, err.Error(), "invalid configuration: API_SECRET is required; DB_NAME is required")
+}
+
+func TestAssign(t *testing.T) {
+	var settings struct {
+		Duration time.Duration
+		Enabled  bool
+		Origins  []string
+		Count    int
+		Name     string
+	}
+	root := reflect.ValueOf(&settings).Elem()
+
+	samples := []struct {
+		field string
+		raw   string
+		want  interface{}
+	}{
+		{"Duration", "1m30s", time.Minute + time.Second*30},
+		{"Enabled", "true", true},
+		{"Enabled", "0", false},
+		{"Origins", "https://a.example.com, https://b.example.com", []string{"https://a.example.com", "https://b.example.com"}},
+		{"Origins", " , https://a.example.com,,", []string{"https://a.example.com"}},
+		{"Origins", "", []string{}},
+		{"Count", "42", 42},
+		{"Name", "goblog", "goblog"},
+	}
+
+	for _, sample := range samples {
+		value := root.FieldByName(sample.field)
+		err := assign(value, sample.raw)
+		if err != nil {
+			t.Fatalf("%s=%q: %v", sample.field, sample.raw, err)
+		}
+		assert.Equal(t, value.Interface(), sample.want)
+	}
+
+	for field, raw := range map[string]string{"Duration": "90", "Enabled": "maybe", "Count": "many"} {
+		if err := assign(root.FieldByName(field), raw); err == nil {
+			t.Errorf("%s=%q: expected an error", field, raw)
+		}
+	}
+}
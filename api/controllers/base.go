@@ -1,43 +1,238 @@
 package controllers
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 
 	"github.com/gorilla/mux"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
-	"github.com/stylll/GoBlog/api/models"
+	"github.com/stylll/GoBlog/api/auth"
+	"github.com/stylll/GoBlog/api/config"
+	"github.com/stylll/GoBlog/api/logger"
+	"github.com/stylll/GoBlog/api/mailer"
+	"github.com/stylll/GoBlog/api/metrics"
+	"github.com/stylll/GoBlog/api/middlewares"
+	"github.com/stylll/GoBlog/api/ratelimit"
 )
 
 type Server struct {
+	Config *config.Config
 	DB     *gorm.DB
 	Router *mux.Router
+	Mailer mailer.Mailer
+	Logger *logger.Logger
+
+	RateLimits ratelimit.Store
+	Lockout    *ratelimit.Lockout
+
+	background sync.WaitGroup
 }
 
-func (server *Server) Initialize(DbUser, DbPassword, DbPort, DbHost, DbName string) {
+func (server *Server) Initialize(cfg *config.Config) error {
 	var err error
 
-	DBURL := "host=%s port=%s user=%s dbname=%s sslmode=disable password=%s"
-	DBURL = fmt.Sprintf(DBURL, DbHost, DbPort, DbUser, DbName, DbPassword)
-	server.DB, err = gorm.Open("postgres", DBURL)
+	server.Config = cfg
+	auth.Configure(cfg.Auth)
+
+	if server.Logger == nil {
+		server.Logger, err = logger.New(os.Stderr, cfg.Log)
+		if err != nil {
+			return err
+		}
+	}
 
+	if server.Mailer == nil {
+		server.Mailer, err = mailer.New(cfg.Mail)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.RateLimit.Enabled {
+		if server.RateLimits == nil {
+			server.RateLimits = ratelimit.NewMemoryStore()
+		}
+		server.Lockout = ratelimit.NewLockout(server.RateLimits, cfg.RateLimit.LockoutThreshold, cfg.RateLimit.LockoutBase, cfg.RateLimit.LockoutMax)
+	}
+
+	db, err := gorm.Open("postgres", cfg.DatabaseURL())
 	if err != nil {
-		fmt.Print("Cannot connect to database")
-		log.Fatal("Error occured: ", err)
+		return fmt.Errorf("cannot connect to database: %v", err)
+	}
+	server.DB = server.Logger.DB(db)
+	metrics.Instrument(server.DB)
+	server.Logger.Info("Connected to database", "host", cfg.Database.Host, "database", cfg.Database.Name)
+
+	server.InitializeRoutes()
+
+	return nil
+}
+
+// Run serves until SIGINT or SIGTERM, then stops accepting connections, waits up to
+// the shutdown timeout for in-flight requests and closes the database pool. Scheduled
+// posts are published in the background while it runs, and metrics are served on
+// their own address when one is configured.
+func (server *Server) Run() error {
+	cfg := server.Config.HTTP
+	httpServer := &http.Server{
+		Addr:         server.Config.Addr,
+		Handler:      server.Handler(),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	schedulerDone := make(chan struct{})
+	if server.Config.PublishInterval > 0 {
+		go server.publishScheduledPosts(schedulerCtx, server.Config.PublishInterval, schedulerDone)
 	} else {
-		fmt.Print("Connected to database")
+		close(schedulerDone)
+	}
+	// the scheduler must stop before the database pool is closed
+	waitForScheduler := func() {
+		stopScheduler()
+		<-schedulerDone
+	}
+
+	errs := make(chan error, 2)
+	go func() {
+		server.Logger.Info("Listening", "addr", httpServer.Addr, "tls", cfg.TLS())
+		if cfg.TLS() {
+			errs <- httpServer.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			errs <- httpServer.ListenAndServe()
+		}
+	}()
+
+	metricsServer := server.metricsServer()
+	if metricsServer != nil {
+		go func() {
+			server.Logger.Info("Serving metrics", "addr", metricsServer.Addr)
+			errs <- metricsServer.ListenAndServe()
+		}()
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	select {
+	case err := <-errs:
+		httpServer.Close()
+		if metricsServer != nil {
+			metricsServer.Close()
+		}
+		waitForScheduler()
+		server.Wait()
+		server.DB.Close()
+		return err
+	case sig := <-stop:
+		server.Logger.Info("Shutting down", "signal", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	err := httpServer.Shutdown(ctx)
+	if err != nil {
+		server.Logger.Warn("Shutdown deadline exceeded, closing remaining connections", "error", err)
+		httpServer.Close()
 	}
+	if metricsServer != nil {
+		metricsServer.Close()
+	}
+
+	waitForScheduler()
+	server.Wait()
+	dbErr := server.DB.Close()
+	if err == nil {
+		err = dbErr
+	}
+	server.Logger.Info("Server stopped")
+
+	return err
+}
+
+// runInBackground runs fn outside the request, e.g. to keep mail latency out of a
+// response. Run waits for it before closing the database pool.
+func (server *Server) runInBackground(fn func()) {
+	server.background.Add(1)
+	go func() {
+		defer server.background.Done()
+		fn()
+	}()
+}
+
+// Wait blocks until the work started with runInBackground has finished.
+func (server *Server) Wait() {
+	server.background.Wait()
+}
+
+// metricsServer serves /metrics on Config.MetricsAddr, away from the public listener,
+// or returns nil when metrics are disabled.
+func (server *Server) metricsServer() *http.Server {
+	if server.Config.MetricsAddr == "" {
+		return nil
+	}
+
+	cfg := server.Config.HTTP
+	router := http.NewServeMux()
+	router.Handle("/metrics", metrics.Default.Handler())
+
+	return &http.Server{
+		Addr:         server.Config.MetricsAddr,
+		Handler:      router,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+}
 
-	server.DB.Debug().AutoMigrate(&models.User{}, &models.Post{})
+// Handler wraps the router with the middleware that applies to every request.
+func (server *Server) Handler() http.Handler {
+	handler := middlewares.SetMiddlewareCORS(server.Config.CORS, server.Router, server.Router)
+	handler = middlewares.SetMiddlewareMetrics(server.Router, handler)
+	return middlewares.SetMiddlewareRequestLogging(server.Logger, server.Router, handler)
+}
 
-	server.Router = mux.NewRouter()
+// limiter builds a named rate limiter, or nil when rate limiting is disabled.
+func (server *Server) limiter(name, limit string, key ratelimit.KeyFunc) *ratelimit.Limiter {
+	if server.RateLimits == nil {
+		return nil
+	}
+
+	parsed, err := ratelimit.ParseLimit(limit)
+	if err != nil {
+		server.Logger.Error("Rate limit disabled", "limiter", name, "error", err)
+		return nil
+	}
+
+	return &ratelimit.Limiter{Name: name, Store: server.RateLimits, Limit: parsed, Key: key}
+}
+
+// db returns the database handle for a request; its SQL log lines carry the request ID.
+func (server *Server) db(r *http.Request) *gorm.DB {
+	return logger.FromContext(r.Context()).DB(server.DB)
+}
+
+// appURL is the public base URL used in links sent to users.
+func (server *Server) appURL() string {
+	if server.Config == nil {
+		return ""
+	}
 
-	server.initializeRoutes()
+	return strings.TrimRight(server.Config.AppURL, "/")
 }
 
-func (server *Server) Run(address string) {
-	fmt.Println("Listening on port 8080")
-	log.Fatal(http.ListenAndServe(address, server.Router))
+// requireVerifiedEmail reports whether only verified users may create posts.
+func (server *Server) requireVerifiedEmail() bool {
+	return server.Config != nil && server.Config.RequireVerifiedEmail
 }
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/stylll/GoBlog/api/dto"
+	"github.com/stylll/GoBlog/api/models"
+	"github.com/stylll/GoBlog/api/responses"
+)
+
+// GetCategories returns every category, nested below its parent.
+func (server *Server) GetCategories(w http.ResponseWriter, r *http.Request) {
+	category := models.Category{}
+	categories, err := category.FindAllCategories(server.db(r))
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, dto.NewCategoryTree(*categories))
+}
+
+func (server *Server) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	category, err := readCategory(r)
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	newCategory, err := category.SaveCategory(server.db(r))
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s%s/%d", r.Host, r.RequestURI, newCategory.ID))
+	responses.JSON(w, http.StatusCreated, dto.NewCategoryResponse(newCategory))
+}
+
+func (server *Server) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		responses.Problem(w, r, errInvalidID)
+		return
+	}
+
+	category, err := readCategory(r)
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	updatedCategory, err := category.UpdateACategory(server.db(r), int(categoryID))
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, dto.NewCategoryResponse(updatedCategory))
+}
+
+func (server *Server) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		responses.Problem(w, r, errInvalidID)
+		return
+	}
+
+	tx := server.db(r).Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	category := models.Category{}
+	_, err = category.DeleteACategory(tx, int(categoryID))
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Entity", fmt.Sprintf("%d", categoryID))
+	responses.JSON(w, http.StatusNoContent, "")
+}
+
+// readCategory decodes, prepares and validates the category in the request body.
+func readCategory(r *http.Request) (models.Category, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return models.Category{}, invalidBody(err)
+	}
+
+	request := dto.CategoryRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		return models.Category{}, invalidBody(err)
+	}
+
+	category := request.ToModel()
+	category.Prepare()
+	return category, category.Validate()
+}
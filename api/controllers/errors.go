@@ -0,0 +1,21 @@
+package controllers
+
+import "github.com/stylll/GoBlog/api/apperror"
+
+var (
+	errInvalidID           = apperror.BadRequest("invalid_id", "ID Invalid")
+	errInvalidBody         = apperror.BadRequest("invalid_body", "Request Body Invalid")
+	errUnauthorized        = apperror.Unauthorized("unauthorized", "Unauthorized")
+	errInvalidCredentials  = apperror.Unauthorized("invalid_credentials", "Incorrect Email or Password")
+	errInvalidRefreshToken = apperror.Unauthorized("invalid_refresh_token", "Invalid Refresh Token")
+	errAccountDisabled     = apperror.Forbidden("account_disabled", "Account disabled")
+	errAccountLocked       = apperror.TooManyRequests("account_locked", "Too many failed login attempts, try again later")
+	errMailFailed          = apperror.New(apperror.KindInternal, "mail_failed", "Cannot send email")
+)
+
+// invalidBody reports a request body that could not be read or decoded as JSON.
+func invalidBody(err error) error {
+	invalid := errInvalidBody.Wrap(err)
+	invalid.Message += ": " + err.Error()
+	return invalid
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/stylll/GoBlog/api/migrations"
+	"github.com/stylll/GoBlog/api/responses"
+)
+
+const readinessTimeout = time.Second * 2
+
+type dependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type readiness struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]dependencyStatus `json:"dependencies"`
+}
+
+// Healthz reports that the process is up; it never touches dependencies.
+func (server *Server) Healthz(w http.ResponseWriter, r *http.Request) {
+	responses.JSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz reports whether the instance can serve traffic: the database answers a ping
+// within the timeout and no migrations are pending.
+func (server *Server) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	db := server.DB.DB()
+	report := readiness{Status: "ok", Dependencies: map[string]dependencyStatus{}}
+
+	report.Dependencies["database"] = check(func() error {
+		return db.PingContext(ctx)
+	})
+	report.Dependencies["migrations"] = check(func() error {
+		pending, err := migrations.Pending(ctx, db)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migrations, first is %04d_%s", len(pending), pending[0].Version, pending[0].Name)
+		}
+		return nil
+	})
+
+	status := http.StatusOK
+	for _, dependency := range report.Dependencies {
+		if dependency.Status != "ok" {
+			report.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	responses.JSON(w, status, report)
+}
+
+func check(fn func() error) dependencyStatus {
+	start := time.Now()
+	err := fn()
+
+	result := dependencyStatus{Status: "ok", LatencyMs: float64(time.Since(start)) / float64(time.Millisecond)}
+	if err != nil {
+		result.Status = "failing"
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -4,56 +4,150 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/jinzhu/gorm"
 	"github.com/stylll/GoBlog/api/auth"
+	"github.com/stylll/GoBlog/api/dto"
 	"github.com/stylll/GoBlog/api/models"
+	"github.com/stylll/GoBlog/api/ratelimit"
 	"github.com/stylll/GoBlog/api/responses"
-	"github.com/stylll/GoBlog/api/utils/formaterror"
 )
 
 func (server *Server) Login(w http.ResponseWriter, r *http.Request) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, r, invalidBody(err))
 		return
 	}
 
-	user := models.User{}
-	err = json.Unmarshal(body, &user)
+	request := dto.LoginRequest{}
+	err = json.Unmarshal(body, &request)
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, r, invalidBody(err))
 		return
 	}
 
+	user := request.ToModel()
 	user.Prepare()
 	err = user.Validate("login")
 	if err != nil {
-		responses.ERROR(w, http.StatusBadRequest, err)
+		responses.Problem(w, r, err)
 		return
 	}
 
-	token, err := server.SignIn(user.Email, user.Password)
+	token, err := server.SignIn(server.db(r), user.Email, user.Password)
+	if locked, ok := err.(*ratelimit.LockedError); ok {
+		w.Header().Set("Retry-After", ratelimit.RetryAfter(locked.RetryAfter))
+		responses.Problem(w, r, errAccountLocked.Wrap(locked))
+		return
+	}
 	if err != nil {
-		formattedError := formaterror.FormatError(err.Error())
-		responses.ERROR(w, http.StatusUnprocessableEntity, formattedError)
+		responses.Problem(w, r, err)
 		return
 	}
 
 	responses.JSON(w, http.StatusOK, token)
 }
 
-func (server *Server) SignIn(email, password string) (string, error) {
+// SignIn checks the credentials and opens a session. Repeated wrong passwords lock the
+// account for a growing period, during which SignIn returns a *ratelimit.LockedError.
+// Unknown emails cost the same bcrypt work and count towards a lockout just like wrong
+// passwords, so neither reveals which accounts exist.
+func (server *Server) SignIn(db *gorm.DB, email, password string) (*auth.TokenDetails, error) {
+	err := server.Lockout.Check(email)
+	if err != nil {
+		return nil, err
+	}
+
 	user := models.User{}
+	err = db.Model(&models.User{}).Where("email = ?", email).Take(&user).Error
+	found := err == nil
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	hash := user.Password
+	if !found {
+		hash = unknownUserHash()
+	}
+
+	err = models.VerifyPassword(hash, password)
+	if err != nil || !found {
+		err = server.Lockout.Fail(email)
+		if err != nil {
+			return nil, err
+		}
+		return nil, errInvalidCredentials
+	}
+
+	err = server.Lockout.Succeed(email)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.StartSession(db, &user)
+}
 
-	err := server.DB.Debug().Model(&models.User{}).Where("email = ?", email).Take(&user).Error
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// unknownUserHash is checked in place of a stored hash when no user has the email.
+func unknownUserHash() string {
+	dummyHashOnce.Do(func() {
+		hash, err := models.Hash("unknown user")
+		if err == nil {
+			dummyHash = string(hash)
+		}
+	})
+
+	return dummyHash
+}
+
+// StartSession opens a new session for an already authenticated user.
+func (server *Server) StartSession(db *gorm.DB, user *models.User) (*auth.TokenDetails, error) {
+	if user.IsDisabled() {
+		return nil, errAccountDisabled
+	}
+
+	session := models.Session{UserID: user.ID}
+	_, err := session.SaveSession(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return issueTokens(db, user, session.ID)
+}
+
+// issueTokens creates a new refresh token for the session and signs a matching access token.
+func issueTokens(db *gorm.DB, user *models.User, sessionId int) (*auth.TokenDetails, error) {
+	refreshToken, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	storedToken := models.RefreshToken{
+		SessionID: sessionId,
+		TokenHash: auth.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(auth.RefreshTokenLifetime),
+	}
+	_, err = storedToken.SaveRefreshToken(db)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	err = models.VerifyPassword(user.Password, password)
+	accessToken, err := auth.CreateToken(user.ID, sessionId, user.Role)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return auth.CreateToken(user.ID)
+	return &auth.TokenDetails{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(auth.AccessTokenLifetime.Seconds()),
+	}, nil
 }
@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stylll/GoBlog/api/apperror"
+	"github.com/stylll/GoBlog/api/auth"
+	"github.com/stylll/GoBlog/api/logger"
+	"github.com/stylll/GoBlog/api/mailer"
+	"github.com/stylll/GoBlog/api/models"
+	"github.com/stylll/GoBlog/api/responses"
+	"github.com/stylll/GoBlog/api/validation"
+)
+
+const passwordResetLifetime = time.Hour * 1
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (server *Server) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Problem(w, r, invalidBody(err))
+		return
+	}
+
+	request := forgotPasswordRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		responses.Problem(w, r, invalidBody(err))
+		return
+	}
+
+	request.Email = strings.TrimSpace(request.Email)
+	err = validation.New().Field("email", "Email", request.Email, validation.Required, validation.Email).Err()
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	// the account is looked up and mailed in the background, so neither the response
+	// nor its timing tells whether the account exists
+	db, log := server.db(r), logger.FromContext(r.Context())
+	server.runInBackground(func() {
+		err := server.sendPasswordReset(db, request.Email)
+		if err != nil {
+			log.Error("Cannot send password reset", "error", err)
+		}
+	})
+
+	responses.JSON(w, http.StatusAccepted, "If the account exists, a password reset link has been sent")
+}
+
+// sendPasswordReset replaces the user's pending reset tokens with a new one and mails
+// its link. Unknown emails are ignored.
+func (server *Server) sendPasswordReset(db *gorm.DB, email string) error {
+	user := models.User{}
+	_, err := user.FindUserByEmail(db, email)
+	if err != nil {
+		if err == models.ErrUserNotFound {
+			return nil
+		}
+		return err
+	}
+
+	err = models.InvalidateUserTokens(db, user.ID, models.TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	resetToken := models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.TokenPurposePasswordReset,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetLifetime),
+	}
+	_, err = resetToken.SaveUserToken(db)
+	if err != nil {
+		return err
+	}
+
+	link, err := server.passwordResetLink(token)
+	if err != nil {
+		return err
+	}
+	err = server.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your GoBlog password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s\n\n"+
+			"If you did not ask for a password reset you can ignore this email.\n",
+			user.Firstname, passwordResetLifetime, link),
+	})
+	if err != nil {
+		return errMailFailed.Wrap(err)
+	}
+
+	return nil
+}
+
+// passwordResetLink points at the frontend page that collects the new password; the
+// API only accepts the token and password posted to /password/reset.
+func (server *Server) passwordResetLink(token string) (string, error) {
+	link, err := url.Parse(server.Config.PasswordResetURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
+
+func (server *Server) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Problem(w, r, invalidBody(err))
+		return
+	}
+
+	request := resetPasswordRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		responses.Problem(w, r, invalidBody(err))
+		return
+	}
+
+	v := validation.New().Field("token", "Token", request.Token, validation.Required)
+	v.Field("password", "Password", request.Password, models.PasswordRules...)
+	err = v.Err()
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	invalidToken := apperror.BadRequest("invalid_token", "Invalid or expired reset token")
+
+	tx := server.db(r).Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	resetToken := models.UserToken{}
+	_, err = resetToken.FindUserTokenByHash(tx, models.TokenPurposePasswordReset, auth.HashToken(request.Token))
+	if err != nil || !resetToken.IsUsable() {
+		responses.Problem(w, r, invalidToken)
+		return
+	}
+
+	consumed, err := resetToken.Consume(tx)
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+	if !consumed {
+		responses.Problem(w, r, invalidToken)
+		return
+	}
+
+	user := models.User{}
+	err = user.UpdatePassword(tx, int64(resetToken.UserID), request.Password)
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	// sign out everywhere in case the old password was compromised
+	err = models.RevokeUserSessions(tx, resetToken.UserID)
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, "Password has been reset")
+}
@@ -1,191 +1,390 @@
 package controllers
 
 import (
+	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	"github.com/stylll/GoBlog/api/apperror"
 	"github.com/stylll/GoBlog/api/auth"
+	"github.com/stylll/GoBlog/api/dto"
 	"github.com/stylll/GoBlog/api/models"
 	"github.com/stylll/GoBlog/api/responses"
-	"github.com/stylll/GoBlog/api/utils/formaterror"
+	"github.com/stylll/GoBlog/api/utils/pagination"
+	"github.com/stylll/GoBlog/api/validation"
 )
 
 func (server *Server) CreatePost(w http.ResponseWriter, r *http.Request) {
-	post := models.Post{}
 	body, err := ioutil.ReadAll(r.Body)
 
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, r, invalidBody(err))
 		return
 	}
 
-	err = json.Unmarshal(body, &post)
+	request := dto.PostRequest{}
+	err = json.Unmarshal(body, &request)
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, r, invalidBody(err))
 		return
 	}
 
-	tokenID, err := auth.ExtractTokenID(r)
-	if err != nil || tokenID == 0 {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized Access"))
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		responses.Problem(w, r, errUnauthorized)
 		return
 	}
 
-	if tokenID != int64(post.AuthorID) {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)))
-		return
+	if server.requireVerifiedEmail() {
+		author := models.User{}
+		_, err = author.FindUserByID(server.db(r), uint64(principal.UserID))
+		if err != nil || !author.IsVerified() {
+			responses.Problem(w, r, apperror.Forbidden("email_not_verified", "Email verification required"))
+			return
+		}
 	}
 
+	post := request.ToModel()
 	post.Prepare()
+	post.AuthorID = int(principal.UserID)
+	switch {
+	case post.Status == models.PostStatusScheduled && post.PublishedAt != nil && !post.PublishedAt.After(time.Now()):
+		// like the publish endpoint, a time that has passed publishes at once
+		post.Status = models.PostStatusPublished
+	case post.Status != models.PostStatusScheduled:
+		post.PublishedAt = nil
+	}
 	err = post.Validate()
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, r, err)
+		return
+	}
+
+	tx := server.db(r).Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	newPost, err := post.SavePost(tx)
+	if err != nil {
+		responses.Problem(w, r, err)
 		return
 	}
 
-	newPost, err := post.SavePost(server.DB)
+	err = tx.Commit().Error
 	if err != nil {
-		formattedError := formaterror.FormatError(err.Error())
-		responses.ERROR(w, http.StatusInternalServerError, formattedError)
+		responses.Problem(w, r, err)
 		return
 	}
 	w.Header().Set("Location", fmt.Sprintf("%s%s/%d", r.Host, r.RequestURI, newPost.ID))
-	responses.JSON(w, http.StatusCreated, newPost)
+	responses.JSON(w, http.StatusCreated, dto.NewPostResponse(newPost))
 }
 
 func (server *Server) GetAllPosts(w http.ResponseWriter, r *http.Request) {
-	post := models.Post{}
+	query := r.URL.Query()
 
-	allPosts, err := post.FindAllPosts(server.DB)
+	page, err := pagination.Parse(query, models.PostSortFields, "created_at")
 	if err != nil {
-		formattedError := formaterror.FormatError(err.Error())
-		responses.ERROR(w, http.StatusInternalServerError, formattedError)
+		responses.Problem(w, r, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, allPosts)
-}
+	filter := models.PostFilter{}
+	if author := query.Get("author"); author != "" {
+		authorID, err := strconv.Atoi(author)
+		if err != nil {
+			responses.Problem(w, r, apperror.BadRequest("invalid_author", "Author must be a user ID"))
+			return
+		}
+		filter.AuthorID = authorID
+	}
 
-func (server *Server) GetPost(w http.ResponseWriter, r *http.Request) {
-	post := models.Post{}
-	vars := mux.Vars(r)
+	filter.Status = query.Get("status")
+	err = validation.New().Field("status", "Status", filter.Status, validation.OneOf(models.PostStatuses...)).Err()
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+	filter.Tag, filter.Category = query.Get("tag"), query.Get("category")
+	setPostVisibility(r, &filter)
 
-	postId, err := strconv.ParseInt(vars["id"], 10, 32)
+	filter.From, filter.To, err = parseDateRange(query)
 	if err != nil {
-		responses.ERROR(w, http.StatusBadRequest, err)
+		responses.Problem(w, r, err)
 		return
 	}
 
-	retrievedPost, err := post.FindPostByID(server.DB, int(postId))
+	post := models.Post{}
+	allPosts, total, more, err := post.FindAllPosts(server.db(r), filter, page)
 	if err != nil {
-		formattedError := formaterror.FormatError(err.Error())
-		responses.ERROR(w, http.StatusInternalServerError, formattedError)
+		responses.Problem(w, r, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, retrievedPost)
+	posts := *allPosts
+	var first, last *pagination.Key
+	if len(posts) > 0 {
+		firstKey, lastKey := posts[0].CursorKey(page.Sort), posts[len(posts)-1].CursorKey(page.Sort)
+		first, last = &firstKey, &lastKey
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.Envelope{
+		Data:  dto.NewPostResponses(posts),
+		Total: total,
+		Limit: page.Limit,
+		Links: page.Links(r.URL, first, last, more),
+	})
 }
 
-func (server *Server) UpdatePost(w http.ResponseWriter, r *http.Request) {
+func (server *Server) GetPost(w http.ResponseWriter, r *http.Request) {
+	post := models.Post{}
 	vars := mux.Vars(r)
+
 	postId, err := strconv.ParseInt(vars["id"], 10, 32)
 	if err != nil {
-		responses.ERROR(w, http.StatusBadRequest, err)
+		responses.Problem(w, r, errInvalidID)
 		return
 	}
 
-	tokenID, err := auth.ExtractTokenID(r)
+	retrievedPost, err := post.FindPostByID(server.db(r), int(postId))
 	if err != nil {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		responses.Problem(w, r, err)
+		return
+	}
+
+	// unpublished posts do not exist as far as other readers are concerned
+	if !canReadPost(r, retrievedPost) {
+		responses.Problem(w, r, models.ErrPostNotFound)
 		return
 	}
 
+	responses.JSON(w, http.StatusOK, dto.NewPostResponse(retrievedPost))
+}
+
+// GetPostBySlug serves the post with the slug, or redirects permanently to its current
+// slug when the post has been renamed since.
+func (server *Server) GetPostBySlug(w http.ResponseWriter, r *http.Request) {
+	slug := mux.Vars(r)["slug"]
+
 	post := models.Post{}
-	foundPost, err := post.FindPostByID(server.DB, int(postId))
+	retrievedPost, err := post.FindPostBySlug(server.db(r), slug)
 	if err != nil {
-		responses.ERROR(w, http.StatusNotFound, errors.New("Post Not Found"))
+		responses.Problem(w, r, err)
 		return
 	}
 
-	if tokenID != int64(foundPost.AuthorID) {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)))
+	if !canReadPost(r, retrievedPost) {
+		responses.Problem(w, r, models.ErrPostNotFound)
 		return
 	}
 
-	body, err := ioutil.ReadAll(r.Body)
+	if retrievedPost.Slug != slug {
+		http.Redirect(w, r, "/posts/by-slug/"+retrievedPost.Slug, http.StatusMovedPermanently)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, dto.NewPostResponse(retrievedPost))
+}
+
+func (server *Server) UpdatePost(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postId, err := strconv.ParseInt(vars["id"], 10, 32)
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, r, errInvalidID)
 		return
 	}
 
-	err = json.Unmarshal(body, &post)
+	existing := models.Post{}
+	foundPost, err := existing.FindPostByID(server.db(r), int(postId))
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, r, err)
 		return
 	}
 
-	// check that userId is the same as AuthorID in the new post to update
-	if tokenID != int64(post.AuthorID) {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)))
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Problem(w, r, invalidBody(err))
 		return
 	}
 
+	request := dto.PostRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		responses.Problem(w, r, invalidBody(err))
+		return
+	}
+
+	post := request.ToModel()
 	post.Prepare()
+	post.AuthorID = foundPost.AuthorID // editors keep the original author
+	post.Status, post.PublishedAt = foundPost.Status, foundPost.PublishedAt
+	if !request.CategoryID.Set {
+		post.CategoryID = foundPost.CategoryID
+	}
 	err = post.Validate()
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, r, err)
 		return
 	}
 
 	post.ID = int(postId) // set the post ID : not sure if this is necessary since post is retrieved from the db at first
 
-	updatedPost, err := post.UpdateAPost(server.DB, int(postId))
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		responses.Problem(w, r, errUnauthorized)
+		return
+	}
+
+	tx := server.db(r).Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	updatedPost, err := post.UpdateAPost(tx, int(postId), int(principal.UserID))
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	err = tx.Commit().Error
 	if err != nil {
-		formattedError := formaterror.FormatError(err.Error())
-		responses.ERROR(w, http.StatusInternalServerError, formattedError)
+		responses.Problem(w, r, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, updatedPost)
+	responses.JSON(w, http.StatusOK, dto.NewPostResponse(updatedPost))
 }
 
 func (server *Server) DeleteAPost(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	postID, err := strconv.ParseInt(vars["id"], 10, 64)
+	postID, err := strconv.ParseInt(vars["id"], 10, 32)
 	if err != nil {
-		responses.ERROR(w, http.StatusBadRequest, err)
+		responses.Problem(w, r, errInvalidID)
+		return
+	}
+
+	post := models.Post{}
+	foundPost, err := post.FindPostByID(server.db(r), int(postID))
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
 	}
 
-	tokenID, err := auth.ExtractTokenID(r)
+	_, err = post.DeleteAPost(server.db(r), int(postID), foundPost.AuthorID)
 	if err != nil {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		responses.Problem(w, r, err)
 		return
 	}
 
+	w.Header().Set("Entity", fmt.Sprintf("%d", postID))
+	responses.JSON(w, http.StatusNoContent, "")
+}
+
+func (server *Server) PublishPost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		responses.Problem(w, r, errInvalidID)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Problem(w, r, invalidBody(err))
+		return
+	}
+
+	// the body is optional; without one the post goes live at once
+	request := dto.PublishRequest{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		err = json.Unmarshal(body, &request)
+		if err != nil {
+			responses.Problem(w, r, invalidBody(err))
+			return
+		}
+	}
+
 	post := models.Post{}
-	foundPost, err := post.FindPostByID(server.DB, int(postID))
+	publishedPost, err := post.Publish(server.db(r), int(postID), request.PublishedAt)
 	if err != nil {
-		responses.ERROR(w, http.StatusNotFound, errors.New("Post Not Found"))
+		responses.Problem(w, r, err)
 		return
 	}
 
-	if tokenID != int64(foundPost.AuthorID) {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)))
+	responses.JSON(w, http.StatusOK, dto.NewPostResponse(publishedPost))
+}
+
+func (server *Server) UnpublishPost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		responses.Problem(w, r, errInvalidID)
 		return
 	}
 
-	_, err = post.DeleteAPost(server.DB, int(postID), int(tokenID))
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		formattedError := formaterror.FormatError(err.Error())
-		responses.ERROR(w, http.StatusInternalServerError, formattedError)
+		responses.Problem(w, r, invalidBody(err))
 		return
 	}
 
-	w.Header().Set("Entity", fmt.Sprintf("%d", postID))
-	responses.JSON(w, http.StatusNoContent, "")
+	request := dto.UnpublishRequest{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		err = json.Unmarshal(body, &request)
+		if err != nil {
+			responses.Problem(w, r, invalidBody(err))
+			return
+		}
+	}
+
+	post := models.Post{}
+	unpublishedPost, err := post.Unpublish(server.db(r), int(postID), request.Archive)
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, dto.NewPostResponse(unpublishedPost))
+}
+
+// setPostVisibility lets callers list their own unpublished posts, and roles that may
+// read any post list all of them.
+func setPostVisibility(r *http.Request, filter *models.PostFilter) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		return
+	}
+
+	filter.ViewerID = int(principal.UserID)
+	filter.ShowUnpublished = principal.Can(auth.ReadAnyPost)
+}
+
+// canReadPost reports whether the caller may see post: published posts are public,
+// others are visible to their author and roles that may read any post.
+func canReadPost(r *http.Request, post *models.Post) bool {
+	if post.IsPublished() {
+		return true
+	}
+
+	principal, ok := auth.FromContext(r.Context())
+	return ok && auth.Authorize(principal, auth.ReadAnyPost, post.AuthorID) == nil
+}
+
+// postOwner resolves the author of the post in the request path for permission checks.
+func (server *Server) postOwner(r *http.Request) (int, error) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		return 0, errInvalidID
+	}
+
+	post := models.Post{}
+	err = server.db(r).Model(&models.Post{}).Select("author_id").Where("id = ?", postID).Take(&post).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return 0, models.ErrPostNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return post.AuthorID, nil
 }
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/stylll/GoBlog/api/apperror"
+)
+
+// parseDateRange reads the from and to query parameters. Both accept RFC 3339
+// timestamps or plain dates; a plain to date includes the whole day.
+func parseDateRange(query url.Values) (*time.Time, *time.Time, error) {
+	var from, to *time.Time
+
+	if value := query.Get("from"); value != "" {
+		t, _, err := parseDate(value)
+		if err != nil {
+			return nil, nil, apperror.BadRequest("invalid_from", "From must be a date or RFC 3339 timestamp")
+		}
+		from = &t
+	}
+
+	if value := query.Get("to"); value != "" {
+		t, dateOnly, err := parseDate(value)
+		if err != nil {
+			return nil, nil, apperror.BadRequest("invalid_to", "To must be a date or RFC 3339 timestamp")
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		to = &t
+	}
+
+	return from, to, nil
+}
+
+func parseDate(value string) (time.Time, bool, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, false, nil
+	}
+
+	t, err = time.Parse("2006-01-02", value)
+	return t, true, err
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/stylll/GoBlog/api/apperror"
+	"github.com/stylll/GoBlog/api/auth"
+	"github.com/stylll/GoBlog/api/dto"
+	"github.com/stylll/GoBlog/api/models"
+	"github.com/stylll/GoBlog/api/responses"
+)
+
+var errInvalidRevision = apperror.BadRequest("invalid_revision", "Revision must be a positive number")
+
+func (server *Server) GetPostRevisions(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		responses.Problem(w, r, errInvalidID)
+		return
+	}
+
+	revision := models.PostRevision{}
+	revisions, err := revision.FindRevisionsByPostID(server.db(r), int(postID))
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, dto.NewPostRevisionResponses(*revisions))
+}
+
+// GetPostRevisionDiff compares the revisions given by the from and to query parameters.
+func (server *Server) GetPostRevisionDiff(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		responses.Problem(w, r, errInvalidID)
+		return
+	}
+
+	query := r.URL.Query()
+	revisions := make([]*models.PostRevision, 2)
+	for i, param := range []string{"from", "to"} {
+		number, err := parseRevision(query.Get(param))
+		if err != nil {
+			responses.Problem(w, r, err)
+			return
+		}
+
+		revision := models.PostRevision{}
+		revisions[i], err = revision.FindRevision(server.db(r), int(postID), number)
+		if err != nil {
+			responses.Problem(w, r, err)
+			return
+		}
+	}
+
+	response, err := dto.NewRevisionDiffResponse(revisions[0], revisions[1])
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, response)
+}
+
+func (server *Server) RestorePostRevision(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	postID, err := strconv.ParseInt(vars["id"], 10, 32)
+	if err != nil {
+		responses.Problem(w, r, errInvalidID)
+		return
+	}
+
+	number, err := parseRevision(vars["rev"])
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		responses.Problem(w, r, errUnauthorized)
+		return
+	}
+
+	tx := server.db(r).Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	post := models.Post{}
+	restoredPost, err := post.RestoreRevision(tx, int(postID), number, int(principal.UserID))
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, dto.NewPostResponse(restoredPost))
+}
+
+func parseRevision(value string) (int, error) {
+	number, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || number < 1 {
+		return 0, errInvalidRevision
+	}
+
+	return int(number), nil
+}
@@ -1,33 +1,162 @@
 package controllers
 
-import "github.com/stylll/GoBlog/api/middlewares"
+import (
+	"github.com/gorilla/mux"
+	"github.com/stylll/GoBlog/api/auth"
+	"github.com/stylll/GoBlog/api/middlewares"
+	"github.com/stylll/GoBlog/api/ratelimit"
+)
 
-func (s *Server) initializeRoutes() {
+// InitializeRoutes registers every route on a new router.
+func (s *Server) InitializeRoutes() {
+	s.Router = mux.NewRouter()
+
+	limits := s.Config.RateLimit
+	byIP := ratelimit.ByIP(limits.TrustProxy)
+	loginLimit := s.limiter("login", limits.Login, byIP)
+	refreshLimit := s.limiter("refresh", limits.Login, byIP)
+	passwordLimit := s.limiter("password", limits.Login, byIP)
+	signupLimit := s.limiter("signup", limits.Signup, byIP)
+	writeLimit := s.limiter("writes", limits.Writes, ratelimit.ByUser(auth.UserID, byIP))
 
 	//Home Route
 	s.Router.HandleFunc("/", middlewares.SetMiddlewareJSON(s.Home)).Methods("GET")
 
+	//Operations Routes
+	s.Router.HandleFunc("/healthz", middlewares.SetMiddlewareJSON(s.Healthz)).Methods("GET")
+	s.Router.HandleFunc("/readyz", middlewares.SetMiddlewareJSON(s.Readyz)).Methods("GET")
+
 	//Login Route
-	s.Router.HandleFunc("/login", middlewares.SetMiddlewareJSON(s.Login)).Methods("POST")
+	s.Router.HandleFunc("/login", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareRateLimit(loginLimit, s.Login))).Methods("POST")
+	s.Router.HandleFunc("/token/refresh", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareRateLimit(refreshLimit, s.RefreshToken))).Methods("POST")
+	s.Router.HandleFunc("/logout", middlewares.SetMiddlewareAuthentication(s.DB, s.Logout)).Methods("POST")
+
+	//Password Routes
+	s.Router.HandleFunc(
+		"/password/forgot",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareRateLimit(passwordLimit, s.ForgotPassword)),
+	).Methods("POST")
+	s.Router.HandleFunc(
+		"/password/reset",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareRateLimit(passwordLimit, s.ResetPassword)),
+	).Methods("POST")
 
 	//User Routes
-	s.Router.HandleFunc("/users", middlewares.SetMiddlewareJSON(s.CreateUser)).Methods("POST")
-	s.Router.HandleFunc("/users", middlewares.SetMiddlewareJSON(s.GetUsers)).Methods("GET")
-	s.Router.HandleFunc("/users/{id}", middlewares.SetMiddlewareJSON(s.GetUser)).Methods("GET")
+	s.Router.HandleFunc("/users", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareRateLimit(signupLimit, s.CreateUser))).Methods("POST")
+	s.Router.HandleFunc("/users", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareOptionalAuthentication(s.DB, s.GetUsers))).Methods("GET")
+	s.Router.HandleFunc("/users/verify", middlewares.SetMiddlewareJSON(s.VerifyEmail)).Methods("GET")
+	s.Router.HandleFunc(
+		"/users/verify/resend",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB, s.ResendVerification)),
+	).Methods("POST")
+	s.Router.HandleFunc("/users/{id}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareOptionalAuthentication(s.DB, s.GetUser))).Methods("GET")
+	s.Router.HandleFunc(
+		"/users/{id}",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewarePermission(auth.ManageUsers, s.userOwner, s.UpdateUser))),
+	).Methods("PUT")
 	s.Router.HandleFunc(
 		"/users/{id}",
-		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UpdateUser)),
+		middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewarePermission(auth.ManageUsers, s.userOwner, s.DeleteUser)),
+	).Methods("DELETE")
+	s.Router.HandleFunc(
+		"/users/{id}/role",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewarePermission(auth.ManageUsers, nil, s.UpdateUserRole))),
 	).Methods("PUT")
-	s.Router.HandleFunc("/users/{id}", middlewares.SetMiddlewareAuthentication(s.DeleteUser)).Methods("DELETE")
 
 	//Post Routes
-	s.Router.HandleFunc("/posts", middlewares.SetMiddlewareJSON(s.CreatePost)).Methods("POST")
-	s.Router.HandleFunc("/posts", middlewares.SetMiddlewareJSON(s.GetAllPosts)).Methods("GET")
-	s.Router.HandleFunc("/posts/{id}", middlewares.SetMiddlewareJSON(s.GetPost)).Methods("GET")
+	s.Router.HandleFunc(
+		"/posts",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewareRateLimit(writeLimit, s.CreatePost))),
+	).Methods("POST")
+	s.Router.HandleFunc("/posts", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareOptionalAuthentication(s.DB, s.GetAllPosts))).Methods("GET")
+	s.Router.HandleFunc("/posts/{id}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareOptionalAuthentication(s.DB, s.GetPost))).Methods("GET")
+	s.Router.HandleFunc(
+		"/posts/by-slug/{slug}",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareOptionalAuthentication(s.DB, s.GetPostBySlug)),
+	).Methods("GET")
+	s.Router.HandleFunc(
+		"/posts/{id}",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewareRateLimit(writeLimit,
+				middlewares.SetMiddlewarePermission(auth.EditAnyPost, s.postOwner, s.UpdatePost)))),
+	).Methods("PUT")
 	s.Router.HandleFunc(
 		"/posts/{id}",
-		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UpdatePost)),
+		middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewareRateLimit(writeLimit,
+				middlewares.SetMiddlewarePermission(auth.DeleteAnyPost, s.postOwner, s.DeleteAPost))),
+	).Methods("DELETE")
+	s.Router.HandleFunc(
+		"/posts/{id}/publish",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewareRateLimit(writeLimit,
+				middlewares.SetMiddlewarePermission(auth.EditAnyPost, s.postOwner, s.PublishPost)))),
+	).Methods("POST")
+	s.Router.HandleFunc(
+		"/posts/{id}/unpublish",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewareRateLimit(writeLimit,
+				middlewares.SetMiddlewarePermission(auth.UnpublishAnyPost, s.postOwner, s.UnpublishPost)))),
+	).Methods("POST")
+	s.Router.HandleFunc(
+		"/posts/{id}/revisions",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewarePermission(auth.EditAnyPost, s.postOwner, s.GetPostRevisions))),
+	).Methods("GET")
+	s.Router.HandleFunc(
+		"/posts/{id}/revisions/diff",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewarePermission(auth.EditAnyPost, s.postOwner, s.GetPostRevisionDiff))),
+	).Methods("GET")
+	s.Router.HandleFunc(
+		"/posts/{id}/revisions/{rev}/restore",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewareRateLimit(writeLimit,
+				middlewares.SetMiddlewarePermission(auth.EditAnyPost, s.postOwner, s.RestorePostRevision)))),
+	).Methods("POST")
+
+	//Tag and Category Routes
+	s.Router.HandleFunc("/tags", middlewares.SetMiddlewareJSON(s.GetTags)).Methods("GET")
+	s.Router.HandleFunc("/categories", middlewares.SetMiddlewareJSON(s.GetCategories)).Methods("GET")
+	s.Router.HandleFunc(
+		"/tags",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewareRateLimit(writeLimit,
+				middlewares.SetMiddlewarePermission(auth.ManageTaxonomy, nil, s.CreateTag)))),
+	).Methods("POST")
+	s.Router.HandleFunc(
+		"/tags/{id}",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewareRateLimit(writeLimit,
+				middlewares.SetMiddlewarePermission(auth.ManageTaxonomy, nil, s.UpdateTag)))),
 	).Methods("PUT")
-	s.Router.HandleFunc("/posts/{id}", middlewares.SetMiddlewareAuthentication(s.DeleteAPost)).Methods("DELETE")
+	s.Router.HandleFunc(
+		"/tags/{id}",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewareRateLimit(writeLimit,
+				middlewares.SetMiddlewarePermission(auth.ManageTaxonomy, nil, s.DeleteTag)))),
+	).Methods("DELETE")
+	s.Router.HandleFunc(
+		"/categories",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewareRateLimit(writeLimit,
+				middlewares.SetMiddlewarePermission(auth.ManageTaxonomy, nil, s.CreateCategory)))),
+	).Methods("POST")
+	s.Router.HandleFunc(
+		"/categories/{id}",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewareRateLimit(writeLimit,
+				middlewares.SetMiddlewarePermission(auth.ManageTaxonomy, nil, s.UpdateCategory)))),
+	).Methods("PUT")
+	s.Router.HandleFunc(
+		"/categories/{id}",
+		middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.DB,
+			middlewares.SetMiddlewareRateLimit(writeLimit,
+				middlewares.SetMiddlewarePermission(auth.ManageTaxonomy, nil, s.DeleteCategory)))),
+	).Methods("DELETE")
 
 }
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/stylll/GoBlog/api/models"
+)
+
+// publishScheduledPosts publishes scheduled posts as they fall due, checking every
+// interval until ctx is cancelled. It closes done when it returns.
+func (server *Server) publishScheduledPosts(ctx context.Context, interval time.Duration, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			count, err := models.PublishDuePosts(server.DB, now)
+			if err != nil {
+				server.Logger.Error("Cannot publish scheduled posts", "error", err)
+				continue
+			}
+			if count > 0 {
+				server.Logger.Info("Published scheduled posts", "count", count)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/stylll/GoBlog/api/dto"
+	"github.com/stylll/GoBlog/api/models"
+	"github.com/stylll/GoBlog/api/responses"
+)
+
+// GetTags returns the tag cloud: every tag with its number of published posts.
+func (server *Server) GetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := models.TagCloud(server.db(r))
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, dto.NewTagCloud(tags))
+}
+
+func (server *Server) CreateTag(w http.ResponseWriter, r *http.Request) {
+	tag, err := readTag(r)
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	newTag, err := tag.SaveTag(server.db(r))
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s%s/%d", r.Host, r.RequestURI, newTag.ID))
+	responses.JSON(w, http.StatusCreated, dto.NewTagResponse(newTag))
+}
+
+func (server *Server) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	tagID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		responses.Problem(w, r, errInvalidID)
+		return
+	}
+
+	tag, err := readTag(r)
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	updatedTag, err := tag.UpdateATag(server.db(r), int(tagID))
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, dto.NewTagResponse(updatedTag))
+}
+
+func (server *Server) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	tagID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		responses.Problem(w, r, errInvalidID)
+		return
+	}
+
+	tx := server.db(r).Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	tag := models.Tag{}
+	_, err = tag.DeleteATag(tx, int(tagID))
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Entity", fmt.Sprintf("%d", tagID))
+	responses.JSON(w, http.StatusNoContent, "")
+}
+
+// readTag decodes, prepares and validates the tag in the request body.
+func readTag(r *http.Request) (models.Tag, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return models.Tag{}, invalidBody(err)
+	}
+
+	request := dto.TagRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		return models.Tag{}, invalidBody(err)
+	}
+
+	tag := request.ToModel()
+	tag.Prepare()
+	return tag, tag.Validate()
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/stylll/GoBlog/api/apperror"
+	"github.com/stylll/GoBlog/api/auth"
+	"github.com/stylll/GoBlog/api/models"
+	"github.com/stylll/GoBlog/api/responses"
+)
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (server *Server) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.Problem(w, r, invalidBody(err))
+		return
+	}
+
+	request := refreshRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		responses.Problem(w, r, invalidBody(err))
+		return
+	}
+
+	if request.RefreshToken == "" {
+		responses.Problem(w, r, apperror.Invalid("refresh_token", "required", "Refresh Token Required"))
+		return
+	}
+
+	tx := server.db(r).Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	storedToken := models.RefreshToken{}
+	_, err = storedToken.FindRefreshTokenByHash(tx, auth.HashToken(request.RefreshToken))
+	if err != nil {
+		responses.Problem(w, r, errInvalidRefreshToken)
+		return
+	}
+
+	session := models.Session{}
+	_, err = session.FindSessionByID(tx, storedToken.SessionID)
+	if err != nil || session.IsRevoked() {
+		responses.Problem(w, r, apperror.Unauthorized("session_revoked", "Session Revoked"))
+		return
+	}
+
+	fresh := storedToken.UsedAt == nil
+	if fresh {
+		fresh, err = storedToken.MarkUsed(tx)
+		if err != nil {
+			responses.Problem(w, r, err)
+			return
+		}
+	}
+
+	if !fresh {
+		// a rotated token was presented again, so the token family is compromised
+		err = session.RevokeSession(tx, session.ID)
+		if err == nil {
+			err = tx.Commit().Error
+		}
+		if err != nil {
+			responses.Problem(w, r, err)
+			return
+		}
+
+		responses.Problem(w, r, apperror.Unauthorized("refresh_token_reused", "Refresh Token Reused"))
+		return
+	}
+
+	if storedToken.IsExpired() {
+		responses.Problem(w, r, apperror.Unauthorized("refresh_token_expired", "Refresh Token Expired"))
+		return
+	}
+
+	// reload the user so role changes take effect on the next access token
+	user := models.User{}
+	_, err = user.FindUserByID(tx, uint64(session.UserID))
+	if err != nil || user.IsDisabled() {
+		responses.Problem(w, r, errInvalidRefreshToken)
+		return
+	}
+
+	tokens, err := issueTokens(tx, &user, session.ID)
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, tokens)
+}
+
+func (server *Server) Logout(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		responses.Problem(w, r, errUnauthorized)
+		return
+	}
+
+	session := models.Session{}
+	err := session.RevokeSession(server.db(r), int(principal.SessionID))
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusNoContent, "")
+}
@@ -2,61 +2,102 @@ package controllers
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/stylll/GoBlog/api/apperror"
 	"github.com/stylll/GoBlog/api/auth"
+	"github.com/stylll/GoBlog/api/dto"
+	"github.com/stylll/GoBlog/api/logger"
 	"github.com/stylll/GoBlog/api/models"
 	"github.com/stylll/GoBlog/api/responses"
-	"github.com/stylll/GoBlog/api/utils/formaterror"
+	"github.com/stylll/GoBlog/api/utils/pagination"
 )
 
 func (server *Server) CreateUser(w http.ResponseWriter, r *http.Request) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, r, invalidBody(err))
 		return
 	}
 
-	user := models.User{}
-	err = json.Unmarshal(body, &user)
+	request := dto.CreateUserRequest{}
+	err = json.Unmarshal(body, &request)
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, r, invalidBody(err))
 		return
 	}
 
+	user := request.ToModel()
 	user.Prepare()
+	user.Role = models.RoleAuthor
+	user.VerifiedAt = nil
 	err = user.Validate("")
 	if err != nil {
-		responses.ERROR(w, http.StatusBadRequest, err)
+		responses.Problem(w, r, err)
 		return
 	}
 
-	userCreated, err := user.SaveUser(server.DB)
+	userCreated, err := user.SaveUser(server.db(r))
 	if err != nil {
-		formattedError := formaterror.FormatError(err.Error())
-		responses.ERROR(w, http.StatusInternalServerError, formattedError)
+		responses.Problem(w, r, err)
 		return
 	}
 
+	err = server.sendVerificationEmail(server.db(r), userCreated)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Cannot send verification email", "user_id", userCreated.ID, "error", err)
+	}
+
 	w.Header().Set("Location", fmt.Sprintf("%s%s/%d", r.Host, r.RequestURI, userCreated.ID))
-	responses.JSON(w, http.StatusCreated, userCreated)
+	responses.JSON(w, http.StatusCreated, dto.NewPrivateUser(userCreated))
 
 }
 
 func (server *Server) GetUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page, err := pagination.Parse(query, models.UserSortFields, "created_at")
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	filter := models.UserFilter{Role: query.Get("role")}
+	if filter.Role != "" && !models.ValidRole(filter.Role) {
+		responses.Problem(w, r, apperror.BadRequest("invalid_role", "Role Invalid"))
+		return
+	}
+
+	filter.From, filter.To, err = parseDateRange(query)
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
 	user := models.User{}
-	users, err := user.FindAllUsers(server.DB)
+	allUsers, total, more, err := user.FindAllUsers(server.db(r), filter, page)
 	if err != nil {
-		responses.ERROR(w, http.StatusInternalServerError, err)
+		responses.Problem(w, r, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, users)
+	users := *allUsers
+	var first, last *pagination.Key
+	if len(users) > 0 {
+		firstKey, lastKey := users[0].CursorKey(page.Sort), users[len(users)-1].CursorKey(page.Sort)
+		first, last = &firstKey, &lastKey
+	}
+
+	responses.JSON(w, http.StatusOK, pagination.Envelope{
+		Data:  dto.NewUserViews(users, auth.UserID(r.Context())),
+		Total: total,
+		Limit: page.Limit,
+		Links: page.Links(r.URL, first, last, more),
+	})
 }
 
 func (server *Server) GetUser(w http.ResponseWriter, r *http.Request) {
@@ -65,67 +106,80 @@ func (server *Server) GetUser(w http.ResponseWriter, r *http.Request) {
 
 	uid, err := strconv.ParseUint(vars["id"], 10, 32)
 	if err != nil {
-		responses.ERROR(w, http.StatusBadRequest, err)
+		responses.Problem(w, r, errInvalidID)
 		return
 	}
 
-	userRetrieved, err := user.FindUserByID(server.DB, uid)
+	userRetrieved, err := user.FindUserByID(server.db(r), uid)
 	if err != nil {
-		responses.ERROR(w, http.StatusInternalServerError, err)
+		responses.Problem(w, r, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, userRetrieved)
+	responses.JSON(w, http.StatusOK, dto.NewUserView(userRetrieved, auth.UserID(r.Context())))
 }
 
 func (server *Server) UpdateUser(w http.ResponseWriter, r *http.Request) {
-	user := models.User{}
 	vars := mux.Vars(r)
 
 	id, err := strconv.ParseInt(vars["id"], 10, 32)
 	if err != nil {
-		responses.ERROR(w, http.StatusBadRequest, err)
+		responses.Problem(w, r, errInvalidID)
 		return
 	}
 
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, r, invalidBody(err))
 		return
 	}
 
-	err = json.Unmarshal(body, &user)
+	request := dto.UpdateUserRequest{}
+	err = json.Unmarshal(body, &request)
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, r, invalidBody(err))
 		return
 	}
 
-	tokenID, err := auth.ExtractTokenID(r)
+	user := request.ToModel()
+	user.Prepare()
+	err = user.Validate("update")
 	if err != nil {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		responses.Problem(w, r, err)
 		return
 	}
 
-	if tokenID != id {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)))
+	tx := server.db(r).Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	current := models.User{}
+	_, err = current.FindUserByID(tx, uint64(id))
+	if err != nil {
+		responses.Problem(w, r, err)
 		return
 	}
 
-	user.Prepare()
-	err = user.Validate("update")
+	updatedUser, err := user.UpdateAUser(tx, int64(id))
 	if err != nil {
-		responses.ERROR(w, http.StatusBadRequest, err)
+		responses.Problem(w, r, err)
 		return
 	}
 
-	updatedUser, err := user.UpdateAUser(server.DB, int64(id))
+	err = tx.Commit().Error
 	if err != nil {
-		formattedError := formaterror.FormatError(err.Error())
-		responses.ERROR(w, http.StatusInternalServerError, formattedError)
+		responses.Problem(w, r, err)
 		return
 	}
 
-	responses.JSON(w, http.StatusOK, updatedUser)
+	// a new address has to be confirmed again before the user may post
+	if updatedUser.Email != current.Email {
+		err = server.sendVerificationEmail(server.db(r), updatedUser)
+		if err != nil {
+			logger.FromContext(r.Context()).Error("Cannot send verification email", "user_id", updatedUser.ID, "error", err)
+		}
+	}
+
+	responses.JSON(w, http.StatusOK, dto.NewUserView(updatedUser, auth.UserID(r.Context())))
 }
 
 func (server *Server) DeleteUser(w http.ResponseWriter, r *http.Request) {
@@ -134,38 +188,77 @@ func (server *Server) DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.ParseInt(vars["id"], 10, 32)
 	if err != nil {
-		responses.ERROR(w, http.StatusBadRequest, err)
+		responses.Problem(w, r, errInvalidID)
 		return
 	}
 
-	body, err := ioutil.ReadAll(r.Body)
+	_, err = user.DeleteAUser(server.db(r), id)
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, r, err)
+		return
+	}
+	w.Header().Set("Entity", fmt.Sprintf("%d", id))
+	responses.JSON(w, http.StatusNoContent, "")
+}
+
+func (server *Server) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.ParseInt(vars["id"], 10, 32)
+	if err != nil {
+		responses.Problem(w, r, errInvalidID)
 		return
 	}
 
-	err = json.Unmarshal(body, &user)
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, r, invalidBody(err))
 		return
 	}
 
-	tokenID, err := auth.ExtractTokenID(r)
+	request := dto.UpdateRoleRequest{}
+	err = json.Unmarshal(body, &request)
 	if err != nil {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		responses.Problem(w, r, invalidBody(err))
 		return
 	}
 
-	if tokenID != 0 && tokenID != id {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)))
+	if !models.ValidRole(request.Role) {
+		responses.Problem(w, r, apperror.BadRequest("invalid_role", "Role Invalid"))
 		return
 	}
 
-	_, err = user.DeleteAUser(server.DB, id)
+	tx := server.db(r).Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	user := models.User{}
+	updatedUser, err := user.UpdateRole(tx, id, request.Role)
 	if err != nil {
-		responses.ERROR(w, http.StatusInternalServerError, err)
+		responses.Problem(w, r, err)
 		return
 	}
-	w.Header().Set("Entity", fmt.Sprintf("%d", id))
-	responses.JSON(w, http.StatusNoContent, "")
+
+	err = tx.Commit().Error
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, dto.NewUserView(updatedUser, auth.UserID(r.Context())))
+}
+
+// userOwner resolves the account in the request path; users own their own account.
+func (server *Server) userOwner(r *http.Request) (int, error) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		return 0, errInvalidID
+	}
+
+	user := models.User{}
+	_, err = user.FindUserByID(server.db(r), id)
+	if err != nil {
+		return 0, err
+	}
+
+	return user.ID, nil
 }
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stylll/GoBlog/api/apperror"
+	"github.com/stylll/GoBlog/api/auth"
+	"github.com/stylll/GoBlog/api/mailer"
+	"github.com/stylll/GoBlog/api/models"
+	"github.com/stylll/GoBlog/api/responses"
+)
+
+const emailVerificationLifetime = time.Hour * 48
+
+// sendVerificationEmail replaces any outstanding verification token for the user and emails a new link.
+func (server *Server) sendVerificationEmail(db *gorm.DB, user *models.User) error {
+	err := models.InvalidateUserTokens(db, user.ID, models.TokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	verificationToken := models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.TokenPurposeEmailVerification,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(emailVerificationLifetime),
+	}
+	_, err = verificationToken.SaveUserToken(db)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/users/verify?token=%s", server.appURL(), url.QueryEscape(token))
+	return server.Mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your GoBlog email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n",
+			user.Firstname, emailVerificationLifetime, link),
+	})
+}
+
+func (server *Server) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		responses.Problem(w, r, apperror.Invalid("token", "required", "Token Required"))
+		return
+	}
+
+	invalidToken := apperror.BadRequest("invalid_token", "Invalid or expired verification token")
+
+	tx := server.db(r).Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	verificationToken := models.UserToken{}
+	_, err := verificationToken.FindUserTokenByHash(tx, models.TokenPurposeEmailVerification, auth.HashToken(token))
+	if err != nil || !verificationToken.IsUsable() {
+		responses.Problem(w, r, invalidToken)
+		return
+	}
+
+	consumed, err := verificationToken.Consume(tx)
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+	if !consumed {
+		responses.Problem(w, r, invalidToken)
+		return
+	}
+
+	user := models.User{}
+	err = user.MarkVerified(tx, int64(verificationToken.UserID))
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	responses.JSON(w, http.StatusOK, "Email verified")
+}
+
+func (server *Server) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		responses.Problem(w, r, errUnauthorized)
+		return
+	}
+
+	user := models.User{}
+	_, err := user.FindUserByID(server.db(r), uint64(principal.UserID))
+	if err != nil {
+		responses.Problem(w, r, err)
+		return
+	}
+
+	if user.IsVerified() {
+		responses.Problem(w, r, apperror.Conflict("already_verified", "Email already verified"))
+		return
+	}
+
+	err = server.sendVerificationEmail(server.db(r), &user)
+	if err != nil {
+		responses.Problem(w, r, errMailFailed.Wrap(err))
+		return
+	}
+
+	responses.JSON(w, http.StatusAccepted, "Verification email sent")
+}
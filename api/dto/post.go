@@ -0,0 +1,107 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/stylll/GoBlog/api/models"
+)
+
+// PostRequest creates or edits a post. Status and PublishedAt only apply on creation;
+// existing posts change status through the publish and unpublish endpoints. Tags are
+// names, created as needed, and replace the post's tags like CategoryID replaces its
+// category. Either one left out of an edit keeps what the post has; an empty list
+// removes every tag and a null category_id removes the category.
+type PostRequest struct {
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	Status      string     `json:"status"`
+	PublishedAt *time.Time `json:"published_at"`
+	CategoryID  OptionalID `json:"category_id"`
+	Tags        *[]string  `json:"tags"`
+}
+
+// OptionalID tells a field left out of the request, with Set false, from an explicit
+// null, with Set true and a nil Value.
+type OptionalID struct {
+	Set   bool
+	Value *int
+}
+
+func (id *OptionalID) UnmarshalJSON(data []byte) error {
+	id.Set = true
+	return json.Unmarshal(data, &id.Value)
+}
+
+// ToModel leaves Tags nil when the request has none, which UpdateAPost reads as
+// keeping the current tags.
+func (req *PostRequest) ToModel() models.Post {
+	var tags []models.Tag
+	if req.Tags != nil {
+		tags = make([]models.Tag, len(*req.Tags))
+		for i, name := range *req.Tags {
+			tags[i] = models.Tag{Name: name}
+		}
+	}
+
+	return models.Post{
+		Title:       req.Title,
+		Content:     req.Content,
+		Status:      req.Status,
+		PublishedAt: req.PublishedAt,
+		CategoryID:  req.CategoryID.Value,
+		Tags:        tags,
+	}
+}
+
+// PublishRequest publishes a post now, or at PublishedAt when that lies in the future.
+type PublishRequest struct {
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+type UnpublishRequest struct {
+	Archive bool `json:"archive"`
+}
+
+type PostResponse struct {
+	ID          int               `json:"id"`
+	Title       string            `json:"title"`
+	Slug        string            `json:"slug"`
+	Content     string            `json:"content"`
+	ContentHTML string            `json:"content_html"`
+	AuthorID    int               `json:"author_id"`
+	Author      PublicUser        `json:"author"`
+	Category    *CategoryResponse `json:"category"`
+	Tags        []TagResponse     `json:"tags"`
+	Status      string            `json:"status"`
+	PublishedAt *time.Time        `json:"published_at"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+func NewPostResponse(p *models.Post) PostResponse {
+	return PostResponse{
+		ID:          p.ID,
+		Title:       p.Title,
+		Slug:        p.Slug,
+		Content:     p.Content,
+		ContentHTML: p.ContentHTML,
+		AuthorID:    p.AuthorID,
+		Author:      NewPublicUser(&p.Author),
+		Category:    NewCategoryReference(p.Category),
+		Tags:        NewTagResponses(p.Tags),
+		Status:      p.Status,
+		PublishedAt: p.PublishedAt,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
+
+func NewPostResponses(posts []models.Post) []PostResponse {
+	responses := make([]PostResponse, len(posts))
+	for i := range posts {
+		responses[i] = NewPostResponse(&posts[i])
+	}
+
+	return responses
+}
@@ -0,0 +1,76 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/stylll/GoBlog/api/models"
+	"github.com/stylll/GoBlog/api/utils/diff"
+)
+
+// PostRevisionResponse is one entry of a post's history. Editor is null once the
+// editor's account has been deleted.
+type PostRevisionResponse struct {
+	Number       int         `json:"number"`
+	Title        string      `json:"title"`
+	Content      string      `json:"content"`
+	EditorID     *int        `json:"editor_id"`
+	Editor       *PublicUser `json:"editor"`
+	RestoredFrom *int        `json:"restored_from"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+func NewPostRevisionResponse(r *models.PostRevision) PostRevisionResponse {
+	response := PostRevisionResponse{
+		Number:       r.Number,
+		Title:        r.Title,
+		Content:      r.Content,
+		EditorID:     r.EditorID,
+		RestoredFrom: r.RestoredFrom,
+		CreatedAt:    r.CreatedAt,
+	}
+	if r.Editor.ID != 0 {
+		editor := NewPublicUser(&r.Editor)
+		response.Editor = &editor
+	}
+
+	return response
+}
+
+func NewPostRevisionResponses(revisions []models.PostRevision) []PostRevisionResponse {
+	responses := make([]PostRevisionResponse, len(revisions))
+	for i := range revisions {
+		responses[i] = NewPostRevisionResponse(&revisions[i])
+	}
+
+	return responses
+}
+
+// RevisionDiffResponse lists the line changes to title and content from one revision
+// to another.
+type RevisionDiffResponse struct {
+	From    int         `json:"from"`
+	To      int         `json:"to"`
+	Title   []diff.Line `json:"title"`
+	Content []diff.Line `json:"content"`
+}
+
+// NewRevisionDiffResponse fails with diff.ErrTooLarge when the revisions are too far
+// apart to compare.
+func NewRevisionDiffResponse(from, to *models.PostRevision) (RevisionDiffResponse, error) {
+	title, err := diff.Lines(from.Title, to.Title)
+	if err != nil {
+		return RevisionDiffResponse{}, err
+	}
+
+	content, err := diff.Lines(from.Content, to.Content)
+	if err != nil {
+		return RevisionDiffResponse{}, err
+	}
+
+	return RevisionDiffResponse{
+		From:    from.Number,
+		To:      to.Number,
+		Title:   title,
+		Content: content,
+	}, nil
+}
@@ -0,0 +1,126 @@
+package dto
+
+import "github.com/stylll/GoBlog/api/models"
+
+type TagRequest struct {
+	Name string `json:"name"`
+}
+
+func (req *TagRequest) ToModel() models.Tag {
+	return models.Tag{Name: req.Name}
+}
+
+type TagResponse struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+func NewTagResponse(t *models.Tag) TagResponse {
+	return TagResponse{
+		ID:   t.ID,
+		Name: t.Name,
+		Slug: t.Slug,
+	}
+}
+
+func NewTagResponses(tags []models.Tag) []TagResponse {
+	responses := make([]TagResponse, len(tags))
+	for i := range tags {
+		responses[i] = NewTagResponse(&tags[i])
+	}
+
+	return responses
+}
+
+// TagCloudEntry is a tag with the number of published posts carrying it.
+type TagCloudEntry struct {
+	TagResponse
+	Count int `json:"count"`
+}
+
+func NewTagCloud(tags []models.TagCount) []TagCloudEntry {
+	cloud := make([]TagCloudEntry, len(tags))
+	for i := range tags {
+		cloud[i] = TagCloudEntry{
+			TagResponse: NewTagResponse(&tags[i].Tag),
+			Count:       tags[i].Count,
+		}
+	}
+
+	return cloud
+}
+
+// CategoryRequest creates or edits a category. A null ParentID makes it a top-level
+// category.
+type CategoryRequest struct {
+	Name     string `json:"name"`
+	ParentID *int   `json:"parent_id"`
+}
+
+func (req *CategoryRequest) ToModel() models.Category {
+	return models.Category{
+		Name:     req.Name,
+		ParentID: req.ParentID,
+	}
+}
+
+type CategoryResponse struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	ParentID *int   `json:"parent_id"`
+}
+
+func NewCategoryResponse(c *models.Category) CategoryResponse {
+	return CategoryResponse{
+		ID:       c.ID,
+		Name:     c.Name,
+		Slug:     c.Slug,
+		ParentID: c.ParentID,
+	}
+}
+
+// NewCategoryReference describes the category of a post, or returns nil for
+// uncategorized posts.
+func NewCategoryReference(c *models.Category) *CategoryResponse {
+	if c == nil {
+		return nil
+	}
+
+	response := NewCategoryResponse(c)
+	return &response
+}
+
+// CategoryNode is a category with its subcategories.
+type CategoryNode struct {
+	CategoryResponse
+	Children []CategoryNode `json:"children"`
+}
+
+// NewCategoryTree nests categories below their parents, keeping the order they came in.
+func NewCategoryTree(categories []models.Category) []CategoryNode {
+	children := map[int][]models.Category{}
+	roots := []models.Category{}
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+		} else {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		}
+	}
+
+	var build func([]models.Category) []CategoryNode
+	build = func(level []models.Category) []CategoryNode {
+		nodes := make([]CategoryNode, len(level))
+		for i := range level {
+			nodes[i] = CategoryNode{
+				CategoryResponse: NewCategoryResponse(&level[i]),
+				Children:         build(children[level[i].ID]),
+			}
+		}
+		return nodes
+	}
+
+	return build(roots)
+}
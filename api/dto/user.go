@@ -0,0 +1,109 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/stylll/GoBlog/api/models"
+)
+
+type CreateUserRequest struct {
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+}
+
+func (req *CreateUserRequest) ToModel() models.User {
+	return models.User{
+		Firstname: req.Firstname,
+		Lastname:  req.Lastname,
+		Email:     req.Email,
+		Password:  req.Password,
+	}
+}
+
+type UpdateUserRequest struct {
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+}
+
+func (req *UpdateUserRequest) ToModel() models.User {
+	return models.User{
+		Firstname: req.Firstname,
+		Lastname:  req.Lastname,
+		Email:     req.Email,
+		Password:  req.Password,
+	}
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (req *LoginRequest) ToModel() models.User {
+	return models.User{
+		Email:    req.Email,
+		Password: req.Password,
+	}
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// PublicUser is the profile anyone may see.
+type PublicUser struct {
+	ID        int       `json:"id"`
+	Firstname string    `json:"firstname"`
+	Lastname  string    `json:"lastname"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PrivateUser is the profile shown to the account owner.
+type PrivateUser struct {
+	PublicUser
+	Email      string     `json:"email"`
+	VerifiedAt *time.Time `json:"verified_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func NewPublicUser(u *models.User) PublicUser {
+	return PublicUser{
+		ID:        u.ID,
+		Firstname: u.Firstname,
+		Lastname:  u.Lastname,
+		Role:      u.Role,
+		CreatedAt: u.CreatedAt,
+	}
+}
+
+func NewPrivateUser(u *models.User) PrivateUser {
+	return PrivateUser{
+		PublicUser: NewPublicUser(u),
+		Email:      u.Email,
+		VerifiedAt: u.VerifiedAt,
+		UpdatedAt:  u.UpdatedAt,
+	}
+}
+
+// NewUserView picks the private view when the viewer is the user and the public one otherwise.
+func NewUserView(u *models.User, viewerID int64) interface{} {
+	if viewerID != 0 && viewerID == int64(u.ID) {
+		return NewPrivateUser(u)
+	}
+
+	return NewPublicUser(u)
+}
+
+func NewUserViews(users []models.User, viewerID int64) []interface{} {
+	views := make([]interface{}, len(users))
+	for i := range users {
+		views[i] = NewUserView(&users[i], viewerID)
+	}
+
+	return views
+}
@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// DB returns a handle on db that logs its SQL through l: every statement at debug,
+// statements slower than the slow-query threshold at warn and failures at error.
+// Which of them are written depends on the SQL level, not the logger's level.
+func (l *Logger) DB(db *gorm.DB) *gorm.DB {
+	scoped := db.New()
+	scoped.LogMode(true)
+	scoped.SetLogger(sqlLogger{l})
+	return scoped
+}
+
+// sqlLogger adapts gorm's Print(v ...interface{}) calls to structured records.
+type sqlLogger struct {
+	log *Logger
+}
+
+func (s sqlLogger) Print(v ...interface{}) {
+	if len(v) < 3 {
+		return
+	}
+
+	l := s.log
+	switch v[0] {
+	case "sql":
+		if len(v) < 6 {
+			return
+		}
+
+		duration, _ := v[2].(time.Duration)
+		level, msg := LevelDebug, "sql"
+		if l.slowQuery > 0 && duration >= l.slowQuery {
+			level, msg = LevelWarn, "slow query"
+		}
+
+		l.write(l.sqlLevel, level, msg,
+			[]interface{}{"query", v[3], "duration_ms", milliseconds(duration), "rows", v[5], "source", v[1]})
+	default:
+		l.write(l.sqlLevel, LevelError, "sql error",
+			[]interface{}{"error", fmt.Sprint(v[2:]...), "source", v[1]})
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
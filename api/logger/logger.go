@@ -0,0 +1,204 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stylll/GoBlog/api/config"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelOff
+)
+
+var levelNames = []string{"debug", "info", "warn", "error", "off"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelOff {
+		return "unknown"
+	}
+
+	return levelNames[l]
+}
+
+func ParseLevel(name string) (Level, error) {
+	for i, level := range levelNames {
+		if strings.EqualFold(name, level) {
+			return Level(i), nil
+		}
+	}
+
+	return LevelInfo, fmt.Errorf("unknown log level %q", name)
+}
+
+// Logger writes one JSON or logfmt record per line. Loggers derived with With
+// share the writer of their parent and add their fields to every record.
+type Logger struct {
+	mu        *sync.Mutex
+	out       io.Writer
+	logfmt    bool
+	level     Level
+	sqlLevel  Level
+	slowQuery time.Duration
+	fields    []interface{}
+}
+
+func New(out io.Writer, cfg config.LogConfig) (*Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlLevel, err := ParseLevel(cfg.SQLLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	var logfmt bool
+	switch cfg.Format {
+	case "json":
+	case "logfmt":
+		logfmt = true
+	default:
+		return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+
+	return &Logger{
+		mu:        &sync.Mutex{},
+		out:       out,
+		logfmt:    logfmt,
+		level:     level,
+		sqlLevel:  sqlLevel,
+		slowQuery: cfg.SlowQueryThreshold,
+	}, nil
+}
+
+// std is used when no logger has been put in a context.
+var std = &Logger{mu: &sync.Mutex{}, out: os.Stderr, level: LevelInfo, sqlLevel: LevelWarn, slowQuery: time.Millisecond * 200}
+
+// With returns a logger that adds the key/value pairs to every record.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	child := *l
+	child.fields = append(append([]interface{}{}, l.fields...), keyvals...)
+	return &child
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.write(l.level, LevelDebug, msg, keyvals)
+}
+func (l *Logger) Info(msg string, keyvals ...interface{}) { l.write(l.level, LevelInfo, msg, keyvals) }
+func (l *Logger) Warn(msg string, keyvals ...interface{}) { l.write(l.level, LevelWarn, msg, keyvals) }
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.write(l.level, LevelError, msg, keyvals)
+}
+
+func (l *Logger) write(min, level Level, msg string, keyvals []interface{}) {
+	if level < min {
+		return
+	}
+
+	pairs := append([]interface{}{
+		"time", time.Now().UTC().Format(time.RFC3339Nano),
+		"level", level.String(),
+		"msg", msg,
+	}, l.fields...)
+	pairs = append(pairs, keyvals...)
+	if len(pairs)%2 != 0 {
+		pairs = append(pairs, "(missing)")
+	}
+
+	var line []byte
+	if l.logfmt {
+		line = encodeLogfmt(pairs)
+	} else {
+		line = encodeJSON(pairs)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(line)
+}
+
+func encodeJSON(pairs []interface{}) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, _ := json.Marshal(fmt.Sprint(pairs[i]))
+		value, err := json.Marshal(jsonValue(pairs[i+1]))
+		if err != nil {
+			value, _ = json.Marshal(fmt.Sprint(pairs[i+1]))
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteString("}\n")
+
+	return buf.Bytes()
+}
+
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return v
+	}
+}
+
+func encodeLogfmt(pairs []interface{}) []byte {
+	buf := &bytes.Buffer{}
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+
+		buf.WriteString(fmt.Sprint(pairs[i]))
+		buf.WriteByte('=')
+
+		value := fmt.Sprint(pairs[i+1])
+		if value == "" || strings.ContainsAny(value, " =\"\t\r\n\\") {
+			value = strconv.Quote(value)
+		}
+		buf.WriteString(value)
+	}
+	buf.WriteByte('\n')
+
+	return buf.Bytes()
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger stored by NewContext, or a default logger writing to stderr.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+
+	return std
+}
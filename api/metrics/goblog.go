@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Default is the registry served on /metrics.
+var Default = NewRegistry()
+
+var (
+	HTTPRequests = Default.NewCounter("goblog_http_requests_total",
+		"HTTP requests by method, route template and status.", "method", "route", "status")
+	HTTPDuration = Default.NewHistogram("goblog_http_request_duration_seconds",
+		"HTTP request latency by method, route template and status.", DefaultBuckets, "method", "route", "status")
+
+	DBQueries = Default.NewCounter("goblog_db_queries_total",
+		"Queries run through gorm by model and operation.", "model", "operation")
+	DBQueryDuration = Default.NewHistogram("goblog_db_query_duration_seconds",
+		"Query latency by model and operation.", []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}, "model", "operation")
+
+	AuthFailures = Default.NewCounter("goblog_auth_failures_total",
+		"Requests rejected by the authentication middleware by reason.", "reason")
+)
+
+var (
+	poolMu sync.Mutex
+	pool   *sql.DB
+)
+
+func poolStat(fn func(sql.DBStats) float64) func() float64 {
+	return func() float64 {
+		poolMu.Lock()
+		db := pool
+		poolMu.Unlock()
+
+		if db == nil {
+			return 0
+		}
+		return fn(db.Stats())
+	}
+}
+
+func init() {
+	Default.NewGaugeFunc("goblog_db_max_open_connections", "Maximum number of open connections to the database.",
+		poolStat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	Default.NewGaugeFunc("goblog_db_open_connections", "Established connections, in use and idle.",
+		poolStat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	Default.NewGaugeFunc("goblog_db_in_use_connections", "Connections currently in use.",
+		poolStat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	Default.NewGaugeFunc("goblog_db_idle_connections", "Idle connections.",
+		poolStat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	Default.NewCounterFunc("goblog_db_wait_count_total", "Connections waited for.",
+		poolStat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	Default.NewCounterFunc("goblog_db_wait_duration_seconds_total", "Time spent waiting for a connection.",
+		poolStat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	Default.NewCounterFunc("goblog_db_max_idle_closed_total", "Connections closed because of the idle limit.",
+		poolStat(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+	Default.NewCounterFunc("goblog_db_max_lifetime_closed_total", "Connections closed because of their maximum lifetime.",
+		poolStat(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+}
+
+const startKey = "metrics:start"
+
+// Instrument reports the pool stats of db and times every query it runs.
+func Instrument(db *gorm.DB) {
+	poolMu.Lock()
+	pool = db.DB()
+	poolMu.Unlock()
+
+	callbacks := db.Callback()
+	callbacks.Create().Before("gorm:create").Register("metrics:before_create", start)
+	callbacks.Create().After("gorm:create").Register("metrics:after_create", observe("create"))
+	callbacks.Query().Before("gorm:query").Register("metrics:before_query", start)
+	callbacks.Query().After("gorm:query").Register("metrics:after_query", observe("query"))
+	callbacks.RowQuery().Before("gorm:row_query").Register("metrics:before_row_query", start)
+	callbacks.RowQuery().After("gorm:row_query").Register("metrics:after_row_query", observe("row_query"))
+	callbacks.Update().Before("gorm:update").Register("metrics:before_update", start)
+	callbacks.Update().After("gorm:update").Register("metrics:after_update", observe("update"))
+	callbacks.Delete().Before("gorm:delete").Register("metrics:before_delete", start)
+	callbacks.Delete().After("gorm:delete").Register("metrics:after_delete", observe("delete"))
+}
+
+func start(scope *gorm.Scope) {
+	scope.Set(startKey, time.Now())
+}
+
+func observe(operation string) func(*gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		started, ok := scope.Get(startKey)
+		if !ok {
+			return
+		}
+
+		model := "unknown"
+		if modelType := scope.GetModelStruct().ModelType; modelType != nil {
+			model = modelType.Name()
+		}
+
+		DBQueries.Inc(model, operation)
+		DBQueryDuration.Observe(time.Since(started.(time.Time)).Seconds(), model, operation)
+	}
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"math"
+	"net/http/httptest"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func scrape(r *Registry) string {
+	rr := httptest.NewRecorder()
+	r.Handler()(rr, httptest.NewRequest("GET", "/metrics", nil))
+	return rr.Body.String()
+}
+
+func TestCounterFormat(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("jobs_total", "Jobs run,\nby queue from C:\\jobs.", "queue", "result")
+	c.Inc("mail", "ok")
+	c.Add(2.5, "mail", "ok")
+	c.Inc(`say "hi"`, "back\\slash\nnewline")
+
+	assert.Equal(t, scrape(r), `# HELP jobs_total Jobs run,\nby queue from C:\\jobs.
+# TYPE jobs_total counter
+jobs_total{queue="mail",result="ok"} 3.5
+jobs_total{queue="say \"hi\"",result="back\\slash\nnewline"} 1
+`)
+}
+
+func TestHistogramFormat(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{1, 0.5}, "route")
+	h.Observe(0.5, "/posts")
+	h.Observe(0.75, "/posts")
+	h.Observe(3, "/posts")
+
+	// buckets are sorted and cumulative, a value on a bound falls in that bucket and
+	// +Inf counts every observation
+	assert.Equal(t, scrape(r), `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/posts",le="0.5"} 1
+latency_seconds_bucket{route="/posts",le="1"} 2
+latency_seconds_bucket{route="/posts",le="+Inf"} 3
+latency_seconds_sum{route="/posts"} 4.25
+latency_seconds_count{route="/posts"} 3
+`)
+}
+
+func TestFuncFormat(t *testing.T) {
+	r := NewRegistry()
+	r.NewGaugeFunc("open_connections", "Open connections.", func() float64 { return 4 })
+	r.NewCounterFunc("overflow_total", "Never stops.", func() float64 { return math.Inf(1) })
+
+	assert.Equal(t, scrape(r), `# HELP open_connections Open connections.
+# TYPE open_connections gauge
+open_connections 4
+# HELP overflow_total Never stops.
+# TYPE overflow_total counter
+overflow_total +Inf
+`)
+}
+
+func TestFormatFloat(t *testing.T) {
+	samples := map[float64]string{
+		0:            "0",
+		0.005:        "0.005",
+		2.5:          "2.5",
+		1e21:         "1e+21",
+		math.Inf(1):  "+Inf",
+		math.Inf(-1): "-Inf",
+	}
+
+	for value, want := range samples {
+		assert.Equal(t, formatFloat(value), want)
+	}
+}
+
+func TestLabelMismatch(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("jobs_total", "Jobs run.", "queue")
+
+	defer func() {
+		assert.Equal(t, recover(), "metrics: jobs_total takes 1 label values, got 2")
+	}()
+	c.Inc("mail", "extra")
+}
@@ -0,0 +1,134 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/stylll/GoBlog/api/config"
+	"github.com/stylll/GoBlog/api/responses"
+)
+
+// SetMiddlewareCORS answers preflight requests and adds CORS headers to cross-origin
+// responses. Routes only register the methods they serve, so a preflight is checked
+// against router with the method it asks for instead of OPTIONS.
+func SetMiddlewareCORS(cfg config.CORSConfig, router *mux.Router, next http.Handler) http.Handler {
+	if len(cfg.AllowedOrigins) == 0 {
+		return next
+	}
+
+	anyOrigin := contains(cfg.AllowedOrigins, "*")
+	allowedMethods := strings.Join(cfg.AllowedMethods, ", ")
+	exposedHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Add("Vary", "Origin")
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if !anyOrigin && !contains(cfg.AllowedOrigins, origin) {
+			if preflight {
+				responses.ERROR(w, http.StatusForbidden, errors.New("Origin Not Allowed"))
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if anyOrigin && !cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposedHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposedHeaders)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+		if !contains(cfg.AllowedMethods, method) {
+			responses.ERROR(w, http.StatusForbidden, errors.New("Method Not Allowed Cross-Origin"))
+			return
+		}
+
+		requested := splitHeaderList(r.Header.Get("Access-Control-Request-Headers"))
+		for _, name := range requested {
+			if !contains(cfg.AllowedHeaders, name) {
+				responses.ERROR(w, http.StatusForbidden, errors.New("Header "+name+" Not Allowed Cross-Origin"))
+				return
+			}
+		}
+
+		status := routeStatus(router, r, method)
+		if status != http.StatusOK {
+			responses.ERROR(w, status, errors.New(http.StatusText(status)))
+			return
+		}
+
+		header.Set("Access-Control-Allow-Methods", allowedMethods)
+		if len(requested) > 0 {
+			header.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+		}
+		if cfg.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// routeStatus reports whether router serves r's path with method: 200 if it does,
+// 405 if the path exists for other methods and 404 if it does not exist at all.
+func routeStatus(router *mux.Router, r *http.Request, method string) int {
+	probe := r.WithContext(r.Context())
+	probe.Method = method
+
+	var match mux.RouteMatch
+	switch {
+	case router.Match(probe, &match) && match.MatchErr == nil:
+		return http.StatusOK
+	case match.MatchErr == mux.ErrMethodMismatch:
+		return http.StatusMethodNotAllowed
+	default:
+		return http.StatusNotFound
+	}
+}
+
+func splitHeaderList(value string) []string {
+	names := []string{}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, http.CanonicalHeaderKey(name))
+		}
+	}
+	return names
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stylll/GoBlog/api/logger"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// statusRecorder remembers the status code and body size written by the handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// SetMiddlewareRequestLogging gives every request an ID (reusing a sane incoming
+// X-Request-ID), stores a logger carrying it in the request context and writes one
+// access line per request once next has answered. router is only used to name the route.
+func SetMiddlewareRequestLogging(log *logger.Logger, router *mux.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(requestIDHeader)
+		if id == "" || len(id) > 64 {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		requestLog := log.With("request_id", id)
+		r = r.WithContext(logger.NewContext(r.Context(), requestLog))
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		access := requestLog.Info
+		if rec.status >= http.StatusInternalServerError {
+			access = requestLog.Error
+		}
+		access("request",
+			"method", r.Method,
+			"route", routeTemplate(router, r),
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration_ms", float64(time.Since(start))/float64(time.Millisecond),
+			"bytes", rec.bytes,
+			"remote_addr", r.RemoteAddr,
+		)
+	})
+}
+
+// routeTemplate returns the path template of the route matching r, e.g. /posts/{id},
+// so that requests for different IDs share a label.
+func routeTemplate(router *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if !router.Match(r, &match) || match.Route == nil {
+		return "unmatched"
+	}
+
+	template, err := match.Route.GetPathTemplate()
+	if err != nil {
+		return "unmatched"
+	}
+
+	return template
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
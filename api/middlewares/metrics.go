@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stylll/GoBlog/api/metrics"
+)
+
+// SetMiddlewareMetrics counts and times every request by method, route template and
+// status. router is only used to name the route.
+func SetMiddlewareMetrics(router *mux.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		labels := []string{methodLabel(r.Method), routeTemplate(router, r), strconv.Itoa(rec.status)}
+		metrics.HTTPRequests.Inc(labels...)
+		metrics.HTTPDuration.Observe(time.Since(start).Seconds(), labels...)
+	})
+}
+
+// methodLabel returns the method for the standard HTTP methods and "other" for the
+// rest, so clients cannot add label values by inventing methods.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	default:
+		return "other"
+	}
+}
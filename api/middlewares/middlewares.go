@@ -1,13 +1,19 @@
 package middlewares
 
 import (
-	"errors"
 	"net/http"
 
+	"github.com/jinzhu/gorm"
+	"github.com/stylll/GoBlog/api/apperror"
 	"github.com/stylll/GoBlog/api/auth"
+	"github.com/stylll/GoBlog/api/logger"
+	"github.com/stylll/GoBlog/api/metrics"
+	"github.com/stylll/GoBlog/api/models"
 	"github.com/stylll/GoBlog/api/responses"
 )
 
+var errUnauthorized = apperror.Unauthorized("unauthorized", "Unauthorized")
+
 func SetMiddlewareJSON(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -15,11 +21,91 @@ func SetMiddlewareJSON(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-func SetMiddlewareAuthentication(next http.HandlerFunc) http.HandlerFunc {
+// authenticate parses the access token and checks that its session is still active.
+// On failure it returns the reason counted in the auth failure metric.
+func authenticate(db *gorm.DB, r *http.Request) (*auth.Principal, string) {
+	token := auth.ExtractToken(r)
+	if token == "" {
+		return nil, "missing_token"
+	}
+
+	principal, err := auth.ParseToken(token)
+	if auth.IsClaimsError(err) {
+		return nil, "invalid_claims"
+	}
+	if err != nil {
+		return nil, "invalid_token"
+	}
+
+	session := models.Session{}
+	_, err = session.FindSessionByID(logger.FromContext(r.Context()).DB(db), int(principal.SessionID))
+	if err != nil {
+		return nil, "unknown_session"
+	}
+	if session.IsRevoked() {
+		return nil, "revoked_session"
+	}
+
+	return principal, ""
+}
+
+// SetMiddlewareAuthentication rejects requests without a valid access token and puts
+// the caller in the request context, see auth.FromContext.
+func SetMiddlewareAuthentication(db *gorm.DB, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		err := auth.TokenValid(r)
+		principal, reason := authenticate(db, r)
+		if principal == nil {
+			metrics.AuthFailures.Inc(reason)
+			responses.Problem(w, r, errUnauthorized)
+			return
+		}
+
+		next(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+	}
+}
+
+// SetMiddlewareOptionalAuthentication puts the caller in the request context when the
+// request carries a valid access token and serves it anonymously otherwise.
+func SetMiddlewareOptionalAuthentication(db *gorm.DB, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := authenticate(db, r)
+		if principal != nil {
+			r = r.WithContext(auth.NewContext(r.Context(), principal))
+		}
+
+		next(w, r)
+	}
+}
+
+// OwnerFunc resolves the ID of the user that owns the resource addressed by the request.
+// It returns a not found error when the resource does not exist.
+type OwnerFunc func(r *http.Request) (int, error)
+
+// SetMiddlewarePermission allows the request when the caller's role grants permission
+// or, if owner is set, when the caller owns the requested resource. The resource is
+// resolved first, so missing resources are 404 for everyone and existing ones 403 for
+// callers that may not touch them.
+func SetMiddlewarePermission(permission auth.Permission, owner OwnerFunc, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.FromContext(r.Context())
+		if !ok {
+			responses.Problem(w, r, errUnauthorized)
+			return
+		}
+
+		var err error
+		ownerID := 0
+		if owner != nil {
+			ownerID, err = owner(r)
+			if err != nil {
+				responses.Problem(w, r, err)
+				return
+			}
+		}
+
+		err = auth.Authorize(principal, permission, ownerID)
 		if err != nil {
-			responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+			responses.Problem(w, r, err)
 			return
 		}
 
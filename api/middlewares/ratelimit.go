@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/stylll/GoBlog/api/apperror"
+	"github.com/stylll/GoBlog/api/logger"
+	"github.com/stylll/GoBlog/api/ratelimit"
+	"github.com/stylll/GoBlog/api/responses"
+)
+
+var errRateLimited = apperror.TooManyRequests("rate_limited", "Too Many Requests")
+
+// SetMiddlewareRateLimit counts the request against limiter and answers 429 once the
+// caller's bucket is empty. A nil limiter lets every request through.
+func SetMiddlewareRateLimit(limiter *ratelimit.Limiter, next http.HandlerFunc) http.HandlerFunc {
+	if limiter == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := limiter.Allow(r)
+		if err != nil {
+			// a broken shared store should not take the API down with it
+			logger.FromContext(r.Context()).Error("Rate limit store failed", "limiter", limiter.Name, "error", err)
+			next(w, r)
+			return
+		}
+
+		ratelimit.WriteHeaders(w.Header(), result)
+		if !result.Allowed {
+			responses.Problem(w, r, errRateLimited)
+			return
+		}
+
+		next(w, r)
+	}
+}
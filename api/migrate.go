@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/stylll/GoBlog/api/migrations"
+	"github.com/stylll/GoBlog/api/seed"
+)
+
+func migrate(args []string) error {
+	flags, loader := newFlagSet("migrate")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	args = flags.Args()
+	if len(args) == 0 {
+		return errors.New("usage: migrate up [version] | down [steps] | status")
+	}
+
+	err = initialize(loader)
+	if err != nil {
+		return err
+	}
+	defer server.DB.Close()
+
+	ctx := context.Background()
+	db := server.DB.DB()
+
+	switch args[0] {
+	case "up":
+		var target int64
+		if len(args) > 1 {
+			version, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid version %q", args[1])
+			}
+			target = version
+		}
+
+		applied, err := migrations.Up(ctx, db, target)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+			steps = n
+		}
+
+		reverted, err := migrations.Down(ctx, db, steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+
+	case "status":
+		statuses, err := migrations.List(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", status.Version, status.Name, state)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+func seedDatabase(args []string) error {
+	flags, loader := newFlagSet("seed")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() > 0 {
+		return errors.New("usage: seed")
+	}
+
+	err = initialize(loader)
+	if err != nil {
+		return err
+	}
+	defer server.DB.Close()
+
+	pending, err := migrations.Pending(context.Background(), server.DB.DB())
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return errors.New("database has pending migrations, run migrate up first")
+	}
+
+	return seed.Load(server.DB)
+}
@@ -0,0 +1,74 @@
+package migrations
+
+// The initial schema uses IF NOT EXISTS so databases previously created by
+// AutoMigrate can adopt versioned migrations without being rebuilt. A users table
+// built before roles and email verification existed keeps its rows and gains those
+// columns; every other table was created with all of its columns from the start.
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: `
+CREATE TABLE IF NOT EXISTS users (
+	id serial PRIMARY KEY,
+	firstname varchar(255) NOT NULL,
+	lastname varchar(255) NOT NULL,
+	email varchar(100) NOT NULL UNIQUE,
+	password varchar(100) NOT NULL,
+	role varchar(20) NOT NULL DEFAULT 'author',
+	verified_at timestamp with time zone,
+	created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP,
+	updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role varchar(20) NOT NULL DEFAULT 'author';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verified_at timestamp with time zone;
+
+CREATE TABLE IF NOT EXISTS posts (
+	id serial PRIMARY KEY,
+	title varchar(255) NOT NULL UNIQUE,
+	content varchar(255) NOT NULL,
+	author_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP,
+	updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_posts_author_id ON posts (author_id);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id serial PRIMARY KEY,
+	user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	revoked_at timestamp with time zone,
+	created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP,
+	updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id serial PRIMARY KEY,
+	session_id integer NOT NULL REFERENCES sessions(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	token_hash varchar(64) NOT NULL UNIQUE,
+	used_at timestamp with time zone,
+	expires_at timestamp with time zone NOT NULL,
+	created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens (session_id);
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+	id serial PRIMARY KEY,
+	user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	purpose varchar(32) NOT NULL,
+	token_hash varchar(64) NOT NULL UNIQUE,
+	used_at timestamp with time zone,
+	expires_at timestamp with time zone NOT NULL,
+	created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user_id ON user_tokens (user_id);
+`,
+		Down: `
+DROP TABLE IF EXISTS user_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS users;
+`,
+	})
+}
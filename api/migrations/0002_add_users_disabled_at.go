@@ -0,0 +1,10 @@
+package migrations
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "add_users_disabled_at",
+		Up:      `ALTER TABLE users ADD COLUMN disabled_at timestamp with time zone;`,
+		Down:    `ALTER TABLE users DROP COLUMN disabled_at;`,
+	})
+}
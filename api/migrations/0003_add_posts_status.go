@@ -0,0 +1,20 @@
+package migrations
+
+// Posts that existed before the publication workflow were public, so they start out published.
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "add_posts_status",
+		Up: `
+ALTER TABLE posts ADD COLUMN status varchar(20) NOT NULL DEFAULT 'draft';
+ALTER TABLE posts ADD COLUMN published_at timestamp with time zone;
+UPDATE posts SET status = 'published', published_at = created_at;
+CREATE INDEX idx_posts_status_published_at ON posts (status, published_at);
+`,
+		Down: `
+DROP INDEX IF EXISTS idx_posts_status_published_at;
+ALTER TABLE posts DROP COLUMN published_at;
+ALTER TABLE posts DROP COLUMN status;
+`,
+	})
+}
@@ -0,0 +1,25 @@
+package migrations
+
+// Existing posts get their current text as revision 1; earlier edits were not kept.
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "add_post_revisions",
+		Up: `
+CREATE TABLE post_revisions (
+	id serial PRIMARY KEY,
+	post_id integer NOT NULL REFERENCES posts(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	number integer NOT NULL,
+	title varchar(255) NOT NULL,
+	content varchar(255) NOT NULL,
+	editor_id integer REFERENCES users(id) ON DELETE SET NULL ON UPDATE CASCADE,
+	restored_from integer,
+	created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_post_revisions_post_id_number ON post_revisions (post_id, number);
+INSERT INTO post_revisions (post_id, number, title, content, editor_id, created_at)
+	SELECT id, 1, title, content, author_id, updated_at FROM posts;
+`,
+		Down: `DROP TABLE IF EXISTS post_revisions;`,
+	})
+}
@@ -0,0 +1,37 @@
+package migrations
+
+// Existing posts get slugs made in SQL: accents are stripped for Latin-1 letters and
+// other characters separate words, which matches the application for most titles.
+// Titles are stored HTML-escaped, so entities are treated as separators too. Slugs
+// that come out the same are told apart by the post ID.
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "add_posts_slug",
+		Up: `
+ALTER TABLE posts ADD COLUMN slug varchar(255);
+UPDATE posts SET slug = left(trim(both '-' from regexp_replace(
+	lower(translate(regexp_replace(title, '&(amp|lt|gt|#34|#39);', ' ', 'g'),
+		'ÀÁÂÃÄÅàáâãäåÇçÈÉÊËèéêëÌÍÎÏìíîïÑñÒÓÔÕÖòóôõöÙÚÛÜùúûüÝýÿ',
+		'AAAAAAaaaaaaCcEEEEeeeeIIIIiiiiNnOOOOOoooooUUUUuuuuYyy')),
+	'[^a-z0-9]+', '-', 'g')), 80);
+UPDATE posts SET slug = 'post' WHERE slug = '';
+UPDATE posts p SET slug = p.slug || '-' || p.id
+	WHERE EXISTS (SELECT 1 FROM posts q WHERE q.slug = p.slug AND q.id < p.id);
+ALTER TABLE posts ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE posts ADD CONSTRAINT posts_slug_key UNIQUE (slug);
+
+CREATE TABLE post_slugs (
+	id serial PRIMARY KEY,
+	post_id integer NOT NULL REFERENCES posts(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	slug varchar(255) NOT NULL UNIQUE,
+	created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_post_slugs_post_id ON post_slugs (post_id);
+`,
+		Down: `
+DROP TABLE IF EXISTS post_slugs;
+ALTER TABLE posts DROP COLUMN slug;
+`,
+	})
+}
@@ -0,0 +1,42 @@
+package migrations
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "add_tags_and_categories",
+		Up: `
+CREATE TABLE categories (
+	id serial PRIMARY KEY,
+	name varchar(100) NOT NULL,
+	slug varchar(255) NOT NULL UNIQUE,
+	parent_id integer REFERENCES categories(id) ON DELETE RESTRICT ON UPDATE CASCADE,
+	created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP,
+	updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_categories_parent_id ON categories (parent_id);
+
+ALTER TABLE posts ADD COLUMN category_id integer REFERENCES categories(id) ON DELETE SET NULL ON UPDATE CASCADE;
+CREATE INDEX idx_posts_category_id ON posts (category_id);
+
+CREATE TABLE tags (
+	id serial PRIMARY KEY,
+	name varchar(50) NOT NULL,
+	slug varchar(255) NOT NULL UNIQUE,
+	created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE post_tags (
+	post_id integer NOT NULL REFERENCES posts(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	tag_id integer NOT NULL REFERENCES tags(id) ON DELETE CASCADE ON UPDATE CASCADE,
+	PRIMARY KEY (post_id, tag_id)
+);
+CREATE INDEX idx_post_tags_tag_id ON post_tags (tag_id);
+`,
+		Down: `
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
+ALTER TABLE posts DROP COLUMN category_id;
+DROP TABLE IF EXISTS categories;
+`,
+	})
+}
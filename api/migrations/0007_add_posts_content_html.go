@@ -0,0 +1,33 @@
+package migrations
+
+// Content used to be stored HTML-escaped in varchar(255); it becomes text, unescaped
+// back to the Markdown the author wrote, in posts and their revisions. content_html
+// starts out empty at version 0 and is rendered by the application the first time each
+// post is read. Going down escapes the content again and narrows the columns, which
+// fails, leaving everything in place, while any post or revision is too long for them.
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "add_posts_content_html",
+		Up: `
+ALTER TABLE posts ALTER COLUMN content TYPE text;
+ALTER TABLE post_revisions ALTER COLUMN content TYPE text;
+ALTER TABLE posts ADD COLUMN content_html text NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN content_html_version integer NOT NULL DEFAULT 0;
+UPDATE posts SET content = replace(replace(replace(replace(replace(content,
+	'&lt;', '<'), '&gt;', '>'), '&#34;', '"'), '&#39;', ''''), '&amp;', '&');
+UPDATE post_revisions SET content = replace(replace(replace(replace(replace(content,
+	'&lt;', '<'), '&gt;', '>'), '&#34;', '"'), '&#39;', ''''), '&amp;', '&');
+`,
+		Down: `
+UPDATE post_revisions SET content = replace(replace(replace(replace(replace(content,
+	'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;');
+UPDATE posts SET content = replace(replace(replace(replace(replace(content,
+	'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;');
+ALTER TABLE post_revisions ALTER COLUMN content TYPE varchar(255);
+ALTER TABLE posts ALTER COLUMN content TYPE varchar(255);
+ALTER TABLE posts DROP COLUMN content_html_version;
+ALTER TABLE posts DROP COLUMN content_html;
+`,
+	})
+}
@@ -0,0 +1,232 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// lockKey identifies the Postgres advisory lock held while migrating so that
+// two instances never apply migrations at the same time.
+const lockKey int64 = 7341205910
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+var registry []Migration
+
+func register(m Migration) {
+	for _, existing := range registry {
+		if existing.Version == m.Version {
+			panic(fmt.Sprintf("migrations: duplicate version %d", m.Version))
+		}
+	}
+
+	registry = append(registry, m)
+	sort.Slice(registry, func(i, j int) bool { return registry[i].Version < registry[j].Version })
+}
+
+func All() []Migration {
+	return append([]Migration{}, registry...)
+}
+
+const createTableSQL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version bigint PRIMARY KEY,
+	name varchar(255) NOT NULL,
+	applied_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// withLock runs fn on a dedicated connection holding the migration advisory lock.
+func withLock(ctx context.Context, db *sql.DB, fn func(conn *sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey)
+	if err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+
+	_, err = conn.ExecContext(ctx, createTableSQL)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+func applied(ctx context.Context, q interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}) (map[int64]time.Time, error) {
+	rows, err := q.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		err = rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+
+	return versions, rows.Err()
+}
+
+func run(ctx context.Context, conn *sql.Conn, m Migration, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	statement := m.Down
+	if up {
+		statement = m.Up
+	}
+
+	_, err = tx.ExecContext(ctx, statement)
+	if err == nil {
+		if up {
+			_, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.Version, m.Name)
+		} else {
+			_, err = tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", m.Version)
+		}
+	}
+
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %d_%s: %v", m.Version, m.Name, err)
+	}
+
+	return tx.Commit()
+}
+
+// Up applies every pending migration up to and including target. A target of
+// 0 applies all of them. It returns the migrations that were applied.
+func Up(ctx context.Context, db *sql.DB, target int64) ([]Migration, error) {
+	done := []Migration{}
+	err := withLock(ctx, db, func(conn *sql.Conn) error {
+		versions, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range registry {
+			if target != 0 && m.Version > target {
+				break
+			}
+			if _, ok := versions[m.Version]; ok {
+				continue
+			}
+
+			err = run(ctx, conn, m, true)
+			if err != nil {
+				return err
+			}
+			done = append(done, m)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Down rolls back the most recent steps applied migrations.
+func Down(ctx context.Context, db *sql.DB, steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, errors.New("steps must be at least 1")
+	}
+
+	done := []Migration{}
+	err := withLock(ctx, db, func(conn *sql.Conn) error {
+		versions, err := applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(registry) - 1; i >= 0 && len(done) < steps; i-- {
+			m := registry[i]
+			if _, ok := versions[m.Version]; !ok {
+				continue
+			}
+
+			err = run(ctx, conn, m, false)
+			if err != nil {
+				return err
+			}
+			done = append(done, m)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// List reports every known migration and when it was applied. It only reads, so it
+// needs no CREATE rights and does not wait for the migration lock; before the first
+// migration has run every migration is reported as pending.
+func List(ctx context.Context, db *sql.DB) ([]Status, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := map[int64]time.Time{}
+	if exists {
+		versions, err = applied(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]Status, len(registry))
+	for i, m := range registry {
+		statuses[i] = Status{Version: m.Version, Name: m.Name}
+		if appliedAt, ok := versions[m.Version]; ok {
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied yet.
+func Pending(ctx context.Context, db *sql.DB) ([]Migration, error) {
+	statuses, err := List(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []Migration{}
+	for i, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, registry[i])
+		}
+	}
+
+	return pending, nil
+}
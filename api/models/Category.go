@@ -0,0 +1,222 @@
+package models
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stylll/GoBlog/api/apperror"
+	"github.com/stylll/GoBlog/api/validation"
+)
+
+const CategoryNameMaxLength = 100
+
+// Category files posts in a tree: a category may have a parent, and listing a category
+// includes the posts of its subcategories.
+type Category struct {
+	ID        int       `gorm:"primary_key;auto_increment" json:"id"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	Slug      string    `gorm:"size:255;not null;unique" json:"slug"`
+	ParentID  *int      `gorm:"index" json:"parent_id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+var errCategoryCycle = apperror.Validation(apperror.FieldError{
+	Field:   "parent_id",
+	Code:    "category_cycle",
+	Message: "Category cannot be moved below itself",
+})
+
+// descendantsSQL selects the IDs of a category and all categories below it.
+const descendantsSQL = `WITH RECURSIVE tree AS (
+	SELECT id FROM categories WHERE %s
+	UNION SELECT categories.id FROM categories JOIN tree ON categories.parent_id = tree.id
+) SELECT id FROM tree`
+
+func (c *Category) Prepare() {
+	c.ID = 0
+	c.Name = html.EscapeString(strings.TrimSpace(c.Name))
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = time.Now()
+}
+
+func (c *Category) Validate() error {
+	return validation.New().Field("name", "Name", c.Name, validation.Required, validation.MaxLength(CategoryNameMaxLength)).Err()
+}
+
+// BeforeCreate gives new categories a unique slug; categories with the same name
+// under different parents are told apart by a numeric suffix.
+func (c *Category) BeforeCreate(tx *gorm.DB) error {
+	taken, err := takenSlugs(tx.Model(&Category{}), titleSlug(c.Name))
+	if err != nil {
+		return err
+	}
+
+	c.Slug = freeSlug(titleSlug(c.Name), taken)
+	return nil
+}
+
+func (c *Category) SaveCategory(db *gorm.DB) (*Category, error) {
+	err := checkCategory(db, "parent_id", c.ParentID)
+	if err != nil {
+		return &Category{}, err
+	}
+
+	err = db.Model(&Category{}).Create(&c).Error
+	if err != nil {
+		return &Category{}, err
+	}
+
+	return c, nil
+}
+
+func (c *Category) FindCategoryByID(db *gorm.DB, categoryId int) (*Category, error) {
+	err := db.Model(&Category{}).Where("id = ?", categoryId).Take(&c).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return &Category{}, ErrCategoryNotFound
+	}
+	if err != nil {
+		return &Category{}, err
+	}
+
+	return c, nil
+}
+
+func (c *Category) FindAllCategories(db *gorm.DB) (*[]Category, error) {
+	categories := []Category{}
+	err := db.Model(&Category{}).Order("name, id").Find(&categories).Error
+	if err != nil {
+		return &[]Category{}, err
+	}
+
+	return &categories, nil
+}
+
+// UpdateACategory renames and moves the category. The slug follows the name, and a
+// category cannot be moved below itself.
+func (c *Category) UpdateACategory(db *gorm.DB, categoryId int) (*Category, error) {
+	current := Category{}
+	_, err := current.FindCategoryByID(db, categoryId)
+	if err != nil {
+		return &Category{}, err
+	}
+
+	err = checkCategory(db, "parent_id", c.ParentID)
+	if err != nil {
+		return &Category{}, err
+	}
+	if c.ParentID != nil {
+		var below int
+		err = db.Model(&Category{}).Where("id IN ("+fmt.Sprintf(descendantsSQL, "id = ?")+")", categoryId).
+			Where("id = ?", *c.ParentID).Count(&below).Error
+		if err != nil {
+			return &Category{}, err
+		}
+		if below > 0 {
+			return &Category{}, errCategoryCycle
+		}
+	}
+
+	slug := current.Slug
+	if c.Name != current.Name {
+		taken, err := takenSlugs(db.Model(&Category{}).Where("id <> ?", categoryId), titleSlug(c.Name))
+		if err != nil {
+			return &Category{}, err
+		}
+		slug = freeSlug(titleSlug(c.Name), taken)
+	}
+
+	err = db.Model(&Category{}).Where("id = ?", categoryId).UpdateColumns(
+		map[string]interface{}{
+			"name":       c.Name,
+			"slug":       slug,
+			"parent_id":  c.ParentID,
+			"updated_at": time.Now(),
+		},
+	).Error
+	if err != nil {
+		return &Category{}, err
+	}
+
+	return c.FindCategoryByID(db, categoryId)
+}
+
+// DeleteACategory deletes a category without subcategories; its posts become
+// uncategorized.
+func (c *Category) DeleteACategory(db *gorm.DB, categoryId int) (int64, error) {
+	_, err := c.FindCategoryByID(db, categoryId)
+	if err != nil {
+		return 0, err
+	}
+
+	var children int
+	err = db.Model(&Category{}).Where("parent_id = ?", categoryId).Count(&children).Error
+	if err != nil {
+		return 0, err
+	}
+	if children > 0 {
+		return 0, ErrCategoryNotEmpty
+	}
+
+	err = db.Model(&Post{}).Where("category_id = ?", categoryId).UpdateColumn("category_id", nil).Error
+	if err != nil {
+		return 0, err
+	}
+
+	db = db.Where("id = ?", categoryId).Delete(&Category{})
+	return db.RowsAffected, db.Error
+}
+
+// checkCategory reports a validation error on field when id names no category.
+func checkCategory(db *gorm.DB, field string, id *int) error {
+	if id == nil {
+		return nil
+	}
+
+	var count int
+	err := db.Model(&Category{}).Where("id = ?", *id).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return apperror.Validation(apperror.FieldError{Field: field, Code: "invalid_category", Message: "Category Not Found"})
+	}
+
+	return nil
+}
+
+// loadCategories fills in the Category of every categorized post with a single IN query.
+func loadCategories(db *gorm.DB, posts ...*Post) error {
+	ids := []int{}
+	for _, post := range posts {
+		post.Category = nil
+		if post.CategoryID != nil {
+			ids = append(ids, *post.CategoryID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	categories := []Category{}
+	err := db.Model(&Category{}).Where("id IN (?)", ids).Find(&categories).Error
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int]*Category, len(categories))
+	for i := range categories {
+		byID[categories[i].ID] = &categories[i]
+	}
+
+	for _, post := range posts {
+		if post.CategoryID != nil {
+			post.Category = byID[*post.CategoryID]
+		}
+	}
+
+	return nil
+}
@@ -1,59 +1,182 @@
 package models
 
 import (
-	"errors"
+	"fmt"
 	"html"
 	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
+	"github.com/stylll/GoBlog/api/markdown"
+	"github.com/stylll/GoBlog/api/utils/pagination"
+	"github.com/stylll/GoBlog/api/utils/slug"
+	"github.com/stylll/GoBlog/api/validation"
 )
 
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+	PostStatusArchived  = "archived"
+)
+
+var PostStatuses = []string{PostStatusDraft, PostStatusScheduled, PostStatusPublished, PostStatusArchived}
+
 type Post struct {
-	ID        int       `gorm:"primary_key;auto_increment" json:"id"`
-	Title     string    `gorm:"size:255;not null;unique" json:"title"`
-	Content   string    `gorm:"size:255;not null;" json:"content"`
-	Author    User      `json:"author"`
-	AuthorID  int       `gorm:"not null" json:"author_id"`
-	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	ID          int        `gorm:"primary_key;auto_increment" json:"id"`
+	Title       string     `gorm:"size:255;not null;unique" json:"title"`
+	Slug        string     `gorm:"size:255;not null;unique" json:"slug"`
+	Content     string     `gorm:"type:text;not null" json:"content"`
+	ContentHTML string     `gorm:"type:text;not null;default:''" json:"content_html"`
+	HTMLVersion int        `gorm:"column:content_html_version;not null;default:0" json:"-"`
+	Author      User       `json:"author"`
+	AuthorID    int        `gorm:"not null" json:"author_id"`
+	Category    *Category  `gorm:"-" json:"category"`
+	CategoryID  *int       `gorm:"index" json:"category_id"`
+	Tags        []Tag      `gorm:"-" json:"tags"`
+	Status      string     `gorm:"size:20;not null;default:'draft';index:idx_posts_status_published_at" json:"status"`
+	PublishedAt *time.Time `gorm:"index:idx_posts_status_published_at" json:"published_at"`
+	CreatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// BeforeCreate gives new posts a unique slug, from Slug if one was given and from the
+// title otherwise, renders their content, defaults them to drafts and dates posts that
+// are created published.
+func (p *Post) BeforeCreate(tx *gorm.DB) error {
+	base := titleSlug(p.Title)
+	if p.Slug != "" {
+		base = slug.Make(p.Slug)
+	}
+	var err error
+	p.Slug, err = uniqueSlug(tx, base, 0)
+	if err != nil {
+		return err
+	}
+
+	err = p.render()
+	if err != nil {
+		return err
+	}
+
+	if p.Status == "" {
+		p.Status = PostStatusDraft
+	}
+	if p.Status == PostStatusPublished && p.PublishedAt == nil {
+		publishedAt := p.CreatedAt
+		if publishedAt.IsZero() {
+			publishedAt = time.Now()
+		}
+		p.PublishedAt = &publishedAt
+	}
+
+	return nil
+}
+
+// render caches the sanitized HTML of the Markdown content.
+func (p *Post) render() error {
+	contentHTML, err := markdown.Render(p.Content)
+	if err != nil {
+		return err
+	}
+
+	p.ContentHTML, p.HTMLVersion = contentHTML, markdown.Version
+	return nil
+}
+
+// renderStale renders again the posts whose HTML was cached by an older renderer,
+// e.g. after an upgrade, and saves the result without touching updated_at.
+func renderStale(db *gorm.DB, posts ...*Post) error {
+	for _, post := range posts {
+		if post.HTMLVersion == markdown.Version {
+			continue
+		}
+
+		err := post.render()
+		if err != nil {
+			return err
+		}
+
+		err = db.Model(&Post{}).Where("id = ?", post.ID).UpdateColumns(
+			map[string]interface{}{
+				"content_html":         post.ContentHTML,
+				"content_html_version": post.HTMLVersion,
+			},
+		).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Post) IsPublished() bool {
+	return p.Status == PostStatusPublished
 }
 
 func (p *Post) Prepare() {
 	p.ID = 0
 	p.Title = html.EscapeString(strings.TrimSpace(p.Title))
-	p.Content = html.EscapeString(strings.TrimSpace(p.Content))
+	p.Content = strings.TrimSpace(p.Content) // Markdown, rendered and sanitized on save
 	p.Author = User{}
+	p.Category = nil
+	for i := range p.Tags {
+		p.Tags[i].Prepare()
+	}
 	p.CreatedAt = time.Now()
 	p.UpdatedAt = time.Now()
 }
 
-func (p *Post) Validate() error {
-	if p.Title == "" {
-		return errors.New("Title Required")
-	}
+// Titles follow the column size and are measured after Prepare has escaped them.
+// Content is stored as text and measured as the Markdown source.
+const (
+	PostTitleMaxLength   = 255
+	PostContentMaxLength = 100000
+)
 
-	if p.Content == "" {
-		return errors.New("Content Required")
+func (p *Post) Validate() error {
+	v := validation.New()
+	v.Field("title", "Title", p.Title, validation.Required, validation.MaxLength(PostTitleMaxLength))
+	v.Field("content", "Content", p.Content, validation.Required, validation.MaxLength(PostContentMaxLength))
+	v.Field("status", "Status", p.Status, validation.OneOf(PostStatuses...))
+	if p.Status == PostStatusScheduled && p.PublishedAt == nil {
+		v.Add("published_at", "required", "Publication Time Required")
 	}
-
 	if p.AuthorID < 1 {
-		return errors.New("Author Required")
+		v.Add("author_id", "required", "Author Required")
+	}
+	if len(p.Tags) > PostTagsMax {
+		v.Add("tags", "too_many", fmt.Sprintf("Posts can have at most %d tags", PostTagsMax))
+	}
+	for i, tag := range p.Tags {
+		v.Field(fmt.Sprintf("tags[%d]", i), "Tag", tag.Name, validation.Required, validation.MaxLength(TagNameMaxLength))
 	}
 
-	return nil
+	return v.Err()
 }
 
+// SavePost creates the post with its category and tags. Run it in a transaction so a
+// failure to tag the post does not leave it behind untagged.
 func (p *Post) SavePost(db *gorm.DB) (*Post, error) {
 	var err error
-	err = db.Debug().Model(&Post{}).Create(&p).Error
+	err = checkCategory(db, "category_id", p.CategoryID)
+	if err != nil {
+		return &Post{}, err
+	}
+
+	err = db.Model(&Post{}).Create(&p).Error
 	if err != nil {
 		return &Post{}, err
 	}
 
 	if p.ID != 0 {
-		// get the author
-		err = db.Debug().Model(&User{}).Where("id = ?", p.AuthorID).Take(&p.Author).Error
+		err = p.saveTags(db, p.ID)
+		if err != nil {
+			return &Post{}, err
+		}
+
+		err = loadRelations(db, p)
 		if err != nil {
 			return &Post{}, err
 		}
@@ -62,74 +185,346 @@ func (p *Post) SavePost(db *gorm.DB) (*Post, error) {
 	return p, nil
 }
 
-func (p *Post) FindAllPosts(db *gorm.DB) (*[]Post, error) {
+// loadRelations fills in the author, category and tags of every post with one query
+// each, and refreshes HTML cached by an older renderer.
+func loadRelations(db *gorm.DB, posts ...*Post) error {
+	for _, load := range []func(*gorm.DB, ...*Post) error{loadAuthors, loadCategories, loadTags, renderStale} {
+		err := load(db, posts...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadAuthors fills in the Author of every post with a single IN query,
+// so loading a page of posts costs the same number of queries at any size.
+func loadAuthors(db *gorm.DB, posts ...*Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(posts))
+	seen := make(map[int]bool, len(posts))
+	for _, post := range posts {
+		if !seen[post.AuthorID] {
+			seen[post.AuthorID] = true
+			ids = append(ids, post.AuthorID)
+		}
+	}
+
+	authors := []User{}
+	err := db.Model(&User{}).Where("id IN (?)", ids).Find(&authors).Error
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int]User, len(authors))
+	for _, author := range authors {
+		byID[author.ID] = author
+	}
+
+	for _, post := range posts {
+		author, ok := byID[post.AuthorID]
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		post.Author = author
+	}
+
+	return nil
+}
+
+// PostFilter narrows a listing. Unpublished posts are only listed for their author,
+// ViewerID, or for everyone when ShowUnpublished is set. Tag and Category are slugs.
+type PostFilter struct {
+	AuthorID        int
+	Status          string
+	Tag             string
+	Category        string
+	From            *time.Time
+	To              *time.Time
+	ViewerID        int
+	ShowUnpublished bool
+}
+
+var PostSortFields = map[string]pagination.Field{
+	"created_at": {Column: "created_at", Time: true, DefaultOrder: pagination.Desc},
+	"updated_at": {Column: "updated_at", Time: true, DefaultOrder: pagination.Desc},
+	"title":      {Column: "title", DefaultOrder: pagination.Asc},
+}
+
+func (f PostFilter) apply(db *gorm.DB) *gorm.DB {
+	switch {
+	case f.ShowUnpublished:
+	case f.ViewerID != 0:
+		db = db.Where("(status = ? OR author_id = ?)", PostStatusPublished, f.ViewerID)
+	default:
+		db = db.Where("status = ?", PostStatusPublished)
+	}
+
+	if f.AuthorID != 0 {
+		db = db.Where("author_id = ?", f.AuthorID)
+	}
+	if f.Status != "" {
+		db = db.Where("status = ?", f.Status)
+	}
+	if f.Tag != "" {
+		db = db.Where("id IN (SELECT post_tags.post_id FROM post_tags JOIN tags ON tags.id = post_tags.tag_id WHERE tags.slug = ?)", f.Tag)
+	}
+	if f.Category != "" {
+		// subcategories count as part of their parent
+		db = db.Where("category_id IN ("+fmt.Sprintf(descendantsSQL, "slug = ?")+")", f.Category)
+	}
+	if f.From != nil {
+		db = db.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		db = db.Where("created_at < ?", *f.To)
+	}
+
+	return db
+}
+
+func (p *Post) CursorKey(sort string) pagination.Key {
+	var value interface{}
+	switch sort {
+	case "updated_at":
+		value = p.UpdatedAt
+	case "title":
+		value = p.Title
+	default:
+		value = p.CreatedAt
+	}
+
+	return pagination.Key{Value: pagination.FormatValue(value), ID: p.ID}
+}
+
+// FindAllPosts returns one page of posts matching filter, the total number of
+// matches and whether the page was cut short.
+func (p *Post) FindAllPosts(db *gorm.DB, filter PostFilter, page *pagination.Params) (*[]Post, int64, bool, error) {
 	var err error
+	var total int64
 	posts := []Post{}
-	err = db.Debug().Model(&Post{}).Limit(100).Find(&posts).Error
+
+	err = filter.apply(db.Model(&Post{})).Count(&total).Error
 	if err != nil {
-		return &posts, err
+		return &posts, 0, false, err
 	}
 
-	if len(posts) > 0 {
-		for i, _ := range posts {
-			err = db.Debug().Model(&User{}).Where("id = ?", posts[i].AuthorID).Take(&posts[i].Author).Error
-			if err != nil {
-				return &[]Post{}, err
-			}
+	err = page.Scope(filter.apply(db.Model(&Post{}))).Find(&posts).Error
+	if err != nil {
+		return &posts, 0, false, err
+	}
+
+	size, more, reverse := page.Window(len(posts))
+	posts = posts[:size]
+	if reverse {
+		for i, j := 0, len(posts)-1; i < j; i, j = i+1, j-1 {
+			posts[i], posts[j] = posts[j], posts[i]
 		}
 	}
 
-	return &posts, nil
+	refs := make([]*Post, len(posts))
+	for i := range posts {
+		refs[i] = &posts[i]
+	}
+
+	err = loadRelations(db, refs...)
+	if err != nil {
+		return &[]Post{}, 0, false, err
+	}
+
+	return &posts, total, more, nil
 }
 
 func (p *Post) FindPostByID(db *gorm.DB, postId int) (*Post, error) {
 	var err error
-	err = db.Debug().Model(&Post{}).Where("id = ?", postId).Take(&p).Error
+	err = db.Model(&Post{}).Where("id = ?", postId).Take(&p).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return &Post{}, ErrPostNotFound
+	}
+	if err != nil {
+		return &Post{}, err
+	}
+
+	err = loadRelations(db, p)
+	if err != nil {
+		return &Post{}, err
+	}
+
+	return p, nil
+}
+
+// UpdateAPost saves the new title, content, category and tags, and records the text as
+// a revision by editorId. Nil Tags keep the current tags; an empty slice removes them.
+// Run it in a transaction so the post and its history change together.
+func (p *Post) UpdateAPost(db *gorm.DB, postId, editorId int) (*Post, error) {
+	err := checkCategory(db, "category_id", p.CategoryID)
 	if err != nil {
 		return &Post{}, err
 	}
 
-	if p.ID == postId {
-		err = db.Debug().Model(&User{}).Where("id = ?", p.AuthorID).Take(&p.Author).Error
+	err = db.Model(&Post{}).Where("id = ?", postId).UpdateColumn("category_id", p.CategoryID).Error
+	if err != nil {
+		return &Post{}, err
+	}
+
+	if p.Tags != nil {
+		err = p.saveTags(db, postId)
 		if err != nil {
 			return &Post{}, err
 		}
 	}
 
-	return p, nil
+	return p.update(db, postId, editorId, nil)
 }
 
-func (p *Post) UpdateAPost(db *gorm.DB, postId int) (*Post, error) {
-	var err error
-	err = db.Debug().Model(&Post{}).Where("id = ?", postId).Updates(Post{
-		Title:     p.Title,
-		Content:   p.Content,
-		UpdatedAt: time.Now(),
-	}).Error
+// RestoreRevision brings back the title and content of an earlier revision. The
+// restore is itself recorded as a new revision, so no history is lost.
+func (p *Post) RestoreRevision(db *gorm.DB, postId, number, editorId int) (*Post, error) {
+	revision := PostRevision{}
+	_, err := revision.FindRevision(db, postId, number)
 	if err != nil {
 		return &Post{}, err
 	}
 
-	if p.ID == postId {
-		err = db.Debug().Model(&User{}).Where("id = ?", p.AuthorID).Take(&p.Author).Error
+	p.Title, p.Content = revision.Title, revision.Content
+	return p.update(db, postId, editorId, &number)
+}
+
+func (p *Post) update(db *gorm.DB, postId, editorId int, restoredFrom *int) (*Post, error) {
+	current := Post{}
+	_, err := current.FindPostByID(db, postId)
+	if err != nil {
+		return &Post{}, err
+	}
+
+	// the slug follows the title, and links to the old one are redirected
+	if p.Title != current.Title {
+		newSlug, err := uniqueSlug(db, titleSlug(p.Title), postId)
 		if err != nil {
 			return &Post{}, err
 		}
+		if newSlug != current.Slug {
+			err = current.changeSlug(db, newSlug)
+			if err != nil {
+				return &Post{}, err
+			}
+		}
+	}
+
+	err = p.render()
+	if err != nil {
+		return &Post{}, err
+	}
+
+	err = db.Model(&Post{}).Where("id = ?", postId).Updates(Post{
+		Title:       p.Title,
+		Content:     p.Content,
+		ContentHTML: p.ContentHTML,
+		HTMLVersion: p.HTMLVersion,
+		UpdatedAt:   time.Now(),
+	}).Error
+	if err != nil {
+		return &Post{}, err
+	}
+
+	// reload so the response carries the stored timestamps and author
+	_, err = p.FindPostByID(db, postId)
+	if err != nil {
+		return &Post{}, err
+	}
+
+	err = p.recordRevision(db, editorId, restoredFrom)
+	if err != nil {
+		return &Post{}, err
 	}
 
 	return p, nil
 }
 
 func (p *Post) DeleteAPost(db *gorm.DB, postId, authorId int) (int64, error) {
-	db = db.Debug().Model(&Post{}).Where("id = ? and authorId = ?", postId, authorId).
+	db = db.Model(&Post{}).Where("id = ? and author_id = ?", postId, authorId).
 		Take(&Post{}).Delete(&Post{})
 
 	if db.Error != nil {
 		if gorm.IsRecordNotFoundError(db.Error) {
-			return 0, errors.New("Post Not Found")
+			return 0, ErrPostNotFound
 		}
 		return 0, db.Error
 	}
 
 	return db.RowsAffected, nil
 }
+
+// Publish makes the post public now, or schedules it when at lies in the future.
+func (p *Post) Publish(db *gorm.DB, postId int, at *time.Time) (*Post, error) {
+	_, err := p.FindPostByID(db, postId)
+	if err != nil {
+		return &Post{}, err
+	}
+
+	now := time.Now()
+	status := PostStatusPublished
+	if at == nil {
+		at = &now
+	} else if at.After(now) {
+		status = PostStatusScheduled
+	}
+
+	if p.Status == PostStatusPublished {
+		return &Post{}, ErrPostAlreadyPublished
+	}
+
+	return p.setStatus(db, postId, status, at)
+}
+
+// Unpublish takes the post back to a draft, or archives it, hiding it from readers.
+// Archived posts keep their original publication time.
+func (p *Post) Unpublish(db *gorm.DB, postId int, archive bool) (*Post, error) {
+	_, err := p.FindPostByID(db, postId)
+	if err != nil {
+		return &Post{}, err
+	}
+
+	status, publishedAt := PostStatusDraft, (*time.Time)(nil)
+	if archive {
+		status, publishedAt = PostStatusArchived, p.PublishedAt
+	}
+
+	if p.Status == status {
+		return &Post{}, ErrPostStatusUnchanged
+	}
+
+	return p.setStatus(db, postId, status, publishedAt)
+}
+
+func (p *Post) setStatus(db *gorm.DB, postId int, status string, publishedAt *time.Time) (*Post, error) {
+	err := db.Model(&Post{}).Where("id = ?", postId).UpdateColumns(
+		map[string]interface{}{
+			"status":       status,
+			"published_at": publishedAt,
+			"updated_at":   time.Now(),
+		},
+	).Error
+	if err != nil {
+		return &Post{}, err
+	}
+
+	return p.FindPostByID(db, postId)
+}
+
+// PublishDuePosts publishes every scheduled post whose time has come. It is safe to
+// run from several instances at once.
+func PublishDuePosts(db *gorm.DB, now time.Time) (int64, error) {
+	db = db.Model(&Post{}).Where("status = ? AND published_at <= ?", PostStatusScheduled, now).UpdateColumns(
+		map[string]interface{}{
+			"status":     PostStatusPublished,
+			"updated_at": now,
+		},
+	)
+
+	return db.RowsAffected, db.Error
+}
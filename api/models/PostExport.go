@@ -0,0 +1,222 @@
+package models
+
+import (
+	"fmt"
+	"html"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ExportedPost is the portable form of a post; authors are referenced by email and
+// the category by the names from its top-level category down, so an export can be
+// imported into a database with different IDs. Exports made before posts had a status
+// import as published. Slugs are kept where they are still free.
+type ExportedPost struct {
+	Title       string     `json:"title"`
+	Slug        string     `json:"slug,omitempty"`
+	Content     string     `json:"content"`
+	AuthorEmail string     `json:"author_email"`
+	Status      string     `json:"status,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	Category    []string   `json:"category,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ExportPosts returns every post, oldest first, in its portable form.
+func ExportPosts(db *gorm.DB) ([]ExportedPost, error) {
+	users := []User{}
+	err := db.Model(&User{}).Select("id, email").Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+
+	emails := make(map[int]string, len(users))
+	for _, user := range users {
+		emails[user.ID] = user.Email
+	}
+
+	paths, err := categoryPaths(db)
+	if err != nil {
+		return nil, err
+	}
+
+	posts := []Post{}
+	err = db.Model(&Post{}).Order("id").Find(&posts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	pointers := make([]*Post, len(posts))
+	for i := range posts {
+		pointers[i] = &posts[i]
+	}
+	err = loadTags(db, pointers...)
+	if err != nil {
+		return nil, err
+	}
+
+	exported := make([]ExportedPost, len(posts))
+	for i, post := range posts {
+		exported[i] = ExportedPost{
+			Title:       post.Title,
+			Slug:        post.Slug,
+			Content:     post.Content,
+			AuthorEmail: emails[post.AuthorID],
+			Status:      post.Status,
+			PublishedAt: post.PublishedAt,
+			CreatedAt:   post.CreatedAt,
+			UpdatedAt:   post.UpdatedAt,
+		}
+		for _, tag := range post.Tags {
+			exported[i].Tags = append(exported[i].Tags, html.UnescapeString(tag.Name))
+		}
+		if post.CategoryID != nil {
+			exported[i].Category = paths[*post.CategoryID]
+		}
+	}
+
+	return exported, nil
+}
+
+// ImportPosts creates the exported posts, resolving authors by email and creating
+// the tags and categories that do not exist yet. Posts whose title is taken are
+// skipped. Run it in a transaction so a failed import leaves nothing behind.
+func ImportPosts(db *gorm.DB, exported []ExportedPost) (created, skipped int, err error) {
+	authors := map[string]int{}
+	for _, item := range exported {
+		authorID, ok := authors[item.AuthorEmail]
+		if !ok {
+			author := User{}
+			_, err = author.FindUserByEmail(db, item.AuthorEmail)
+			if err != nil {
+				return created, skipped, fmt.Errorf("post %q: author %q: %v", item.Title, item.AuthorEmail, err)
+			}
+			authorID = author.ID
+			authors[item.AuthorEmail] = authorID
+		}
+
+		var existing int
+		err = db.Model(&Post{}).Where("title = ?", item.Title).Count(&existing).Error
+		if err != nil {
+			return created, skipped, err
+		}
+		if existing > 0 {
+			skipped++
+			continue
+		}
+
+		post := Post{
+			Title:       item.Title,
+			Slug:        item.Slug,
+			Content:     item.Content,
+			AuthorID:    authorID,
+			Status:      item.Status,
+			PublishedAt: item.PublishedAt,
+			Tags:        make([]Tag, len(item.Tags)),
+			CreatedAt:   item.CreatedAt,
+			UpdatedAt:   item.UpdatedAt,
+		}
+		if post.Status == "" {
+			post.Status = PostStatusPublished
+		}
+		for i, name := range item.Tags {
+			post.Tags[i] = Tag{Name: name}
+			post.Tags[i].Prepare()
+		}
+		err = post.Validate()
+		if err != nil {
+			return created, skipped, fmt.Errorf("post %q: %v", item.Title, err)
+		}
+
+		if len(item.Category) > 0 {
+			category, err := findOrCreateCategoryPath(db, item.Category)
+			if err != nil {
+				return created, skipped, fmt.Errorf("post %q: category %q: %v", item.Title, item.Category, err)
+			}
+			post.CategoryID = &category.ID
+		}
+
+		_, err = post.SavePost(db)
+		if err != nil {
+			return created, skipped, fmt.Errorf("post %q: %v", item.Title, err)
+		}
+		created++
+	}
+
+	return created, skipped, nil
+}
+
+// categoryPaths maps the ID of every category to the names from its top-level
+// category down to it.
+func categoryPaths(db *gorm.DB) (map[int][]string, error) {
+	categories := []Category{}
+	err := db.Model(&Category{}).Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	paths := make(map[int][]string, len(categories))
+	var path func(id int) []string
+	path = func(id int) []string {
+		if names, ok := paths[id]; ok {
+			return names
+		}
+
+		category := byID[id]
+		names := []string{}
+		if category.ParentID != nil {
+			names = append(names, path(*category.ParentID)...)
+		}
+		names = append(names, html.UnescapeString(category.Name))
+		paths[id] = names
+		return names
+	}
+	for id := range byID {
+		path(id)
+	}
+
+	return paths, nil
+}
+
+// findOrCreateCategoryPath returns the category reached by following names from a
+// top-level category down, creating the categories that do not exist yet.
+func findOrCreateCategoryPath(db *gorm.DB, names []string) (*Category, error) {
+	var parentID *int
+	category := &Category{}
+	for _, name := range names {
+		category = &Category{Name: name}
+		category.Prepare()
+		err := category.Validate()
+		if err != nil {
+			return nil, err
+		}
+
+		query := db.Model(&Category{}).Where("name = ?", category.Name)
+		if parentID == nil {
+			query = query.Where("parent_id IS NULL")
+		} else {
+			query = query.Where("parent_id = ?", *parentID)
+		}
+		err = query.Take(category).Error
+		if gorm.IsRecordNotFoundError(err) {
+			category.ParentID = parentID
+			_, err = category.SaveCategory(db)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		id := category.ID
+		parentID = &id
+	}
+
+	return category, nil
+}
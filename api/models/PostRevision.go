@@ -0,0 +1,119 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// PostRevision is an immutable snapshot of a post, taken when it is created and after
+// every edit. Revisions are numbered from 1 per post.
+type PostRevision struct {
+	ID           int       `gorm:"primary_key;auto_increment" json:"id"`
+	PostID       int       `gorm:"not null;unique_index:idx_post_revisions_post_id_number" json:"post_id"`
+	Number       int       `gorm:"not null;unique_index:idx_post_revisions_post_id_number" json:"number"`
+	Title        string    `gorm:"size:255;not null" json:"title"`
+	Content      string    `gorm:"type:text;not null" json:"content"`
+	Editor       User      `json:"editor"`
+	EditorID     *int      `json:"editor_id"`
+	RestoredFrom *int      `json:"restored_from"`
+	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// AfterCreate records the first revision of a new post, credited to its author.
+func (p *Post) AfterCreate(tx *gorm.DB) error {
+	return p.recordRevision(tx, p.AuthorID, nil)
+}
+
+// recordRevision snapshots the post as the next revision. Callers updating a post must
+// do so in the same transaction first: the row lock taken by the update keeps
+// concurrent edits from claiming the same number.
+func (p *Post) recordRevision(db *gorm.DB, editorId int, restoredFrom *int) error {
+	var last int
+	err := db.Model(&PostRevision{}).Where("post_id = ?", p.ID).Select("COALESCE(MAX(number), 0)").Row().Scan(&last)
+	if err != nil {
+		return err
+	}
+
+	revision := PostRevision{
+		PostID:       p.ID,
+		Number:       last + 1,
+		Title:        p.Title,
+		Content:      p.Content,
+		EditorID:     &editorId,
+		RestoredFrom: restoredFrom,
+		CreatedAt:    time.Now(),
+	}
+
+	return db.Model(&PostRevision{}).Create(&revision).Error
+}
+
+func (r *PostRevision) FindRevisionsByPostID(db *gorm.DB, postId int) (*[]PostRevision, error) {
+	revisions := []PostRevision{}
+	err := db.Model(&PostRevision{}).Where("post_id = ?", postId).Order("number desc").Find(&revisions).Error
+	if err != nil {
+		return &[]PostRevision{}, err
+	}
+
+	refs := make([]*PostRevision, len(revisions))
+	for i := range revisions {
+		refs[i] = &revisions[i]
+	}
+
+	err = loadEditors(db, refs...)
+	if err != nil {
+		return &[]PostRevision{}, err
+	}
+
+	return &revisions, nil
+}
+
+func (r *PostRevision) FindRevision(db *gorm.DB, postId, number int) (*PostRevision, error) {
+	err := db.Model(&PostRevision{}).Where("post_id = ? AND number = ?", postId, number).Take(&r).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return &PostRevision{}, ErrRevisionNotFound
+	}
+	if err != nil {
+		return &PostRevision{}, err
+	}
+
+	err = loadEditors(db, r)
+	if err != nil {
+		return &PostRevision{}, err
+	}
+
+	return r, nil
+}
+
+// loadEditors fills in the Editor of every revision with a single IN query. Revisions
+// whose editor has since been deleted keep an empty Editor.
+func loadEditors(db *gorm.DB, revisions ...*PostRevision) error {
+	ids := []int{}
+	for _, revision := range revisions {
+		if revision.EditorID != nil {
+			ids = append(ids, *revision.EditorID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	editors := []User{}
+	err := db.Model(&User{}).Where("id IN (?)", ids).Find(&editors).Error
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int]User, len(editors))
+	for _, editor := range editors {
+		byID[editor.ID] = editor
+	}
+
+	for _, revision := range revisions {
+		if revision.EditorID != nil {
+			revision.Editor = byID[*revision.EditorID]
+		}
+	}
+
+	return nil
+}
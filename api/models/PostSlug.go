@@ -0,0 +1,109 @@
+package models
+
+import (
+	"fmt"
+	"html"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stylll/GoBlog/api/utils/slug"
+)
+
+// PostSlug is a slug a post used to have. Old slugs stay reserved for their post so
+// links to them can be redirected to the current one.
+type PostSlug struct {
+	ID        int       `gorm:"primary_key;auto_increment" json:"id"`
+	PostID    int       `gorm:"not null;index" json:"post_id"`
+	Slug      string    `gorm:"size:255;not null;unique" json:"slug"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// uniqueSlug returns base, or base with the lowest free numeric suffix, skipping
+// slugs held by other posts now or in the past. postId is the post being renamed,
+// or 0 for a new post.
+func uniqueSlug(db *gorm.DB, base string, postId int) (string, error) {
+	current, err := takenSlugs(db.Model(&Post{}).Where("id <> ?", postId), base)
+	if err != nil {
+		return "", err
+	}
+
+	previous, err := takenSlugs(db.Model(&PostSlug{}).Where("post_id <> ?", postId), base)
+	if err != nil {
+		return "", err
+	}
+
+	return freeSlug(base, append(current, previous...)), nil
+}
+
+// takenSlugs returns the slugs in scope that base or a suffixed base would clash with.
+func takenSlugs(scope *gorm.DB, base string) ([]string, error) {
+	// slugs never contain LIKE wildcards, so base needs no escaping
+	taken := []string{}
+	err := scope.Where("slug = ? OR slug LIKE ?", base, base+"-%").Pluck("slug", &taken).Error
+
+	return taken, err
+}
+
+func freeSlug(base string, taken []string) string {
+	used := make(map[string]bool, len(taken))
+	for _, s := range taken {
+		used[s] = true
+	}
+
+	candidate := base
+	for n := 2; used[candidate]; n++ {
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	return candidate
+}
+
+// titleSlug makes the slug base for a title, which Prepare has HTML-escaped.
+func titleSlug(title string) string {
+	return slug.Make(html.UnescapeString(title))
+}
+
+// changeSlug moves the post to newSlug, keeping the old one in its history. A post
+// going back to one of its former slugs takes it out of the history again.
+func (p *Post) changeSlug(db *gorm.DB, newSlug string) error {
+	err := db.Where("post_id = ? AND slug = ?", p.ID, newSlug).Delete(&PostSlug{}).Error
+	if err != nil {
+		return err
+	}
+
+	err = db.Model(&PostSlug{}).Create(&PostSlug{PostID: p.ID, Slug: p.Slug, CreatedAt: time.Now()}).Error
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&Post{}).Where("id = ?", p.ID).UpdateColumn("slug", newSlug).Error
+}
+
+// FindPostBySlug finds the post by its current slug or one it used to have; callers
+// compare the slug with p.Slug to tell the two apart.
+func (p *Post) FindPostBySlug(db *gorm.DB, s string) (*Post, error) {
+	var err error
+	err = db.Model(&Post{}).Where("slug = ?", s).Take(&p).Error
+	if gorm.IsRecordNotFoundError(err) {
+		previous := PostSlug{}
+		err = db.Model(&PostSlug{}).Where("slug = ?", s).Take(&previous).Error
+		if gorm.IsRecordNotFoundError(err) {
+			return &Post{}, ErrPostNotFound
+		}
+		if err != nil {
+			return &Post{}, err
+		}
+
+		return p.FindPostByID(db, previous.PostID)
+	}
+	if err != nil {
+		return &Post{}, err
+	}
+
+	err = loadRelations(db, p)
+	if err != nil {
+		return &Post{}, err
+	}
+
+	return p, nil
+}
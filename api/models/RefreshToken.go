@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type RefreshToken struct {
+	ID        int        `gorm:"primary_key;auto_increment" json:"id"`
+	SessionID int        `gorm:"not null;index" json:"session_id"`
+	TokenHash string     `gorm:"size:64;not null;unique" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+func (t *RefreshToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+func (t *RefreshToken) SaveRefreshToken(db *gorm.DB) (*RefreshToken, error) {
+	err := db.Model(&RefreshToken{}).Create(&t).Error
+	if err != nil {
+		return &RefreshToken{}, err
+	}
+
+	return t, nil
+}
+
+func (t *RefreshToken) FindRefreshTokenByHash(db *gorm.DB, hash string) (*RefreshToken, error) {
+	err := db.Model(&RefreshToken{}).Where("token_hash = ?", hash).Take(&t).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return &RefreshToken{}, ErrRefreshTokenNotFound
+	}
+
+	if err != nil {
+		return &RefreshToken{}, err
+	}
+
+	return t, nil
+}
+
+// MarkUsed flags the token as consumed. It reports false when another request
+// already used it, which callers must treat as token reuse.
+func (t *RefreshToken) MarkUsed(db *gorm.DB) (bool, error) {
+	now := time.Now()
+	db = db.Model(&RefreshToken{}).Where("id = ? AND used_at IS NULL", t.ID).UpdateColumn("used_at", now)
+	if db.Error != nil {
+		return false, db.Error
+	}
+
+	if db.RowsAffected == 0 {
+		return false, nil
+	}
+
+	t.UsedAt = &now
+	return true, nil
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type Session struct {
+	ID        int        `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    int        `gorm:"not null;index" json:"user_id"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+func (s *Session) IsRevoked() bool {
+	return s.RevokedAt != nil
+}
+
+func (s *Session) SaveSession(db *gorm.DB) (*Session, error) {
+	err := db.Model(&Session{}).Create(&s).Error
+	if err != nil {
+		return &Session{}, err
+	}
+
+	return s, nil
+}
+
+func (s *Session) FindSessionByID(db *gorm.DB, sessionId int) (*Session, error) {
+	err := db.Model(&Session{}).Where("id = ?", sessionId).Take(&s).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return &Session{}, ErrSessionNotFound
+	}
+
+	if err != nil {
+		return &Session{}, err
+	}
+
+	return s, nil
+}
+
+func (s *Session) RevokeSession(db *gorm.DB, sessionId int) error {
+	return db.Model(&Session{}).Where("id = ? AND revoked_at IS NULL", sessionId).UpdateColumns(
+		map[string]interface{}{
+			"revoked_at": time.Now(),
+			"updated_at": time.Now(),
+		},
+	).Error
+}
+
+func RevokeUserSessions(db *gorm.DB, userID int) error {
+	return db.Model(&Session{}).Where("user_id = ? AND revoked_at IS NULL", userID).UpdateColumns(
+		map[string]interface{}{
+			"revoked_at": time.Now(),
+			"updated_at": time.Now(),
+		},
+	).Error
+}
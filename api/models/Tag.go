@@ -0,0 +1,202 @@
+package models
+
+import (
+	"html"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stylll/GoBlog/api/utils/slug"
+	"github.com/stylll/GoBlog/api/validation"
+)
+
+const (
+	TagNameMaxLength = 50
+	PostTagsMax      = 10
+)
+
+// Tag labels posts. Tags are identified by their slug, so "Go" and "go" are one tag.
+type Tag struct {
+	ID        int       `gorm:"primary_key;auto_increment" json:"id"`
+	Name      string    `gorm:"size:50;not null" json:"name"`
+	Slug      string    `gorm:"size:255;not null;unique" json:"slug"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+type PostTag struct {
+	PostID int `gorm:"primary_key;auto_increment:false"`
+	TagID  int `gorm:"primary_key;auto_increment:false;index"`
+}
+
+// TagCount is a tag with the number of published posts carrying it.
+type TagCount struct {
+	Tag
+	Count int `json:"count"`
+}
+
+func (t *Tag) Prepare() {
+	t.ID = 0
+	t.Name = html.EscapeString(strings.TrimSpace(t.Name))
+	t.Slug = slug.Make(html.UnescapeString(t.Name))
+	t.CreatedAt = time.Now()
+}
+
+func (t *Tag) Validate() error {
+	return validation.New().Field("name", "Name", t.Name, validation.Required, validation.MaxLength(TagNameMaxLength)).Err()
+}
+
+func (t *Tag) SaveTag(db *gorm.DB) (*Tag, error) {
+	err := db.Model(&Tag{}).Create(&t).Error
+	if err != nil {
+		return &Tag{}, err
+	}
+
+	return t, nil
+}
+
+func (t *Tag) FindTagByID(db *gorm.DB, tagId int) (*Tag, error) {
+	err := db.Model(&Tag{}).Where("id = ?", tagId).Take(&t).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return &Tag{}, ErrTagNotFound
+	}
+	if err != nil {
+		return &Tag{}, err
+	}
+
+	return t, nil
+}
+
+// TagCloud returns every tag with its count of published posts, most used first.
+func TagCloud(db *gorm.DB) ([]TagCount, error) {
+	tags := []TagCount{}
+	err := db.Table("tags").
+		Select("tags.*, COUNT(posts.id) AS count").
+		Joins("LEFT JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("LEFT JOIN posts ON posts.id = post_tags.post_id AND posts.status = ?", PostStatusPublished).
+		Group("tags.id").
+		Order("count desc, tags.name").
+		Scan(&tags).Error
+
+	return tags, err
+}
+
+// UpdateATag renames the tag; its slug follows the name.
+func (t *Tag) UpdateATag(db *gorm.DB, tagId int) (*Tag, error) {
+	err := db.Model(&Tag{}).Where("id = ?", tagId).UpdateColumns(
+		map[string]interface{}{
+			"name": t.Name,
+			"slug": t.Slug,
+		},
+	).Error
+	if err != nil {
+		return &Tag{}, err
+	}
+
+	return t.FindTagByID(db, tagId)
+}
+
+func (t *Tag) DeleteATag(db *gorm.DB, tagId int) (int64, error) {
+	err := db.Where("tag_id = ?", tagId).Delete(&PostTag{}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	db = db.Where("id = ?", tagId).Delete(&Tag{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	if db.RowsAffected == 0 {
+		return 0, ErrTagNotFound
+	}
+
+	return db.RowsAffected, nil
+}
+
+// findOrCreateTags returns the tags with the given names, creating those that do not
+// exist yet. Names are expected to be prepared; duplicates collapse into one tag.
+func findOrCreateTags(db *gorm.DB, names []string) ([]Tag, error) {
+	tags := []Tag{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		tag := Tag{Name: name, Slug: slug.Make(html.UnescapeString(name))}
+		if seen[tag.Slug] {
+			continue
+		}
+		seen[tag.Slug] = true
+
+		err := db.Where(Tag{Slug: tag.Slug}).Attrs(Tag{Name: tag.Name, CreatedAt: time.Now()}).FirstOrCreate(&tag).Error
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// saveTags replaces the tags of the post with p.Tags, creating new ones by name.
+func (p *Post) saveTags(db *gorm.DB, postId int) error {
+	names := make([]string, len(p.Tags))
+	for i, tag := range p.Tags {
+		names[i] = tag.Name
+	}
+
+	tags, err := findOrCreateTags(db, names)
+	if err != nil {
+		return err
+	}
+
+	err = db.Where("post_id = ?", postId).Delete(&PostTag{}).Error
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		err = db.Create(&PostTag{PostID: postId, TagID: tag.ID}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type postTagRow struct {
+	PostID int
+	Tag
+}
+
+// loadTags fills in the Tags of every post with a single query, ordered by name.
+func loadTags(db *gorm.DB, posts ...*Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+		post.Tags = []Tag{}
+	}
+
+	rows := []postTagRow{}
+	err := db.Table("tags").
+		Select("post_tags.post_id, tags.*").
+		Joins("JOIN post_tags ON post_tags.tag_id = tags.id").
+		Where("post_tags.post_id IN (?)", ids).
+		Order("tags.name").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int]*Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+	for _, row := range rows {
+		post := byID[row.PostID]
+		post.Tags = append(post.Tags, row.Tag)
+	}
+
+	return nil
+}
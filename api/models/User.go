@@ -1,26 +1,36 @@
 package models
 
 import (
-	"errors"
-	"fmt"
 	"html"
-	"log"
 	"strings"
 	"time"
 
-	"github.com/badoux/checkmail"
 	"github.com/jinzhu/gorm"
+	"github.com/stylll/GoBlog/api/apperror"
+	"github.com/stylll/GoBlog/api/utils/pagination"
+	"github.com/stylll/GoBlog/api/validation"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleAuthor = "author"
+)
+
+var Roles = []string{RoleAdmin, RoleEditor, RoleAuthor}
+
 type User struct {
-	ID        int       `gorm:"primary_key;auto_increment" json:"id"`
-	Firstname string    `gorm:"size:255;not null" json:"firstname"`
-	Lastname  string    `gorm:"size:255;not null" json:"lastname"`
-	Email     string    `gorm:"size:100;not null;unique" json:"email"`
-	Password  string    `gorm:"size:100;not null" json:"password"`
-	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	ID         int        `gorm:"primary_key;auto_increment" json:"id"`
+	Firstname  string     `gorm:"size:255;not null" json:"firstname"`
+	Lastname   string     `gorm:"size:255;not null" json:"lastname"`
+	Email      string     `gorm:"size:100;not null;unique" json:"email"`
+	Password   string     `gorm:"size:100;not null" json:"-"`
+	Role       string     `gorm:"size:20;not null;default:'author'" json:"role"`
+	VerifiedAt *time.Time `json:"verified_at"`
+	DisabledAt *time.Time `json:"disabled_at"`
+	CreatedAt  time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 }
 
 func Hash(password string) ([]byte, error) {
@@ -48,65 +58,46 @@ func (u *User) Prepare() {
 	u.Email = html.EscapeString(strings.TrimSpace(u.Email))
 }
 
+var (
+	nameRules  = []validation.Rule{validation.Required, validation.MaxLength(255)}
+	emailRules = []validation.Rule{validation.Required, validation.MaxLength(100), validation.Email}
+
+	// PasswordRules apply to every new password; bcrypt refuses anything past 72 bytes.
+	PasswordRules = []validation.Rule{validation.Required, validation.MinLength(8), validation.MaxBytes(72), validation.StrongPassword}
+)
+
+// Validate reports every invalid field. Logins only check that the credentials are
+// present so that passwords set before the strength rules still work.
 func (u *User) Validate(operation string) error {
-	switch strings.ToLower(operation) {
-	case "update":
-		if u.Firstname == "" {
-			return errors.New("Firstname Required")
-		}
-		if u.Lastname == "" {
-			return errors.New("Lastname Required")
-		}
-		if u.Email == "" {
-			return errors.New("Email Required")
-		}
-		if err := checkmail.ValidateFormat(u.Email); err != nil {
-			return errors.New("Email Invalid")
-		}
-		if u.Password == "" {
-			return errors.New("Password Required")
-		}
+	v := validation.New()
 
-		return nil
+	if strings.ToLower(operation) == "login" {
+		v.Field("email", "Email", u.Email, emailRules...)
+		v.Field("password", "Password", u.Password, validation.Required)
+		return v.Err()
+	}
 
-	case "login":
-		if u.Email == "" {
-			return errors.New("Email Required")
-		}
-		if err := checkmail.ValidateFormat(u.Email); err != nil {
-			fmt.Println("Email invalid")
-			return errors.New("Email Invalid")
-		}
-		if u.Password == "" {
-			return errors.New("Password Required")
-		}
+	v.Field("firstname", "Firstname", u.Firstname, nameRules...)
+	v.Field("lastname", "Lastname", u.Lastname, nameRules...)
+	v.Field("email", "Email", u.Email, emailRules...)
+	v.Field("password", "Password", u.Password, PasswordRules...)
 
-		return nil
+	return v.Err()
+}
 
-	default:
-		if u.Firstname == "" {
-			return errors.New("Firstname Required")
-		}
-		if u.Lastname == "" {
-			return errors.New("Lastname Required")
-		}
-		if u.Email == "" {
-			return errors.New("Email Required")
-		}
-		if err := checkmail.ValidateFormat(u.Email); err != nil {
-			return errors.New("Email Invalid")
-		}
-		if u.Password == "" {
-			return errors.New("Password Required")
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
 		}
-
-		return nil
 	}
+
+	return false
 }
 
 func (u *User) SaveUser(db *gorm.DB) (*User, error) {
 	var err error
-	err = db.Debug().Create(&u).Error
+	err = db.Create(&u).Error
 	if err != nil {
 		return &User{}, err
 	}
@@ -114,24 +105,80 @@ func (u *User) SaveUser(db *gorm.DB) (*User, error) {
 	return u, nil
 }
 
-func (u *User) FindAllUsers(db *gorm.DB) (*[]User, error) {
+type UserFilter struct {
+	Role string
+	From *time.Time
+	To   *time.Time
+}
+
+var UserSortFields = map[string]pagination.Field{
+	"created_at": {Column: "created_at", Time: true, DefaultOrder: pagination.Desc},
+	"updated_at": {Column: "updated_at", Time: true, DefaultOrder: pagination.Desc},
+	"lastname":   {Column: "lastname", DefaultOrder: pagination.Asc},
+}
+
+func (f UserFilter) apply(db *gorm.DB) *gorm.DB {
+	if f.Role != "" {
+		db = db.Where("role = ?", f.Role)
+	}
+	if f.From != nil {
+		db = db.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		db = db.Where("created_at < ?", *f.To)
+	}
+
+	return db
+}
+
+func (u *User) CursorKey(sort string) pagination.Key {
+	var value interface{}
+	switch sort {
+	case "updated_at":
+		value = u.UpdatedAt
+	case "lastname":
+		value = u.Lastname
+	default:
+		value = u.CreatedAt
+	}
+
+	return pagination.Key{Value: pagination.FormatValue(value), ID: u.ID}
+}
+
+// FindAllUsers returns one page of users matching filter, the total number of
+// matches and whether the page was cut short.
+func (u *User) FindAllUsers(db *gorm.DB, filter UserFilter, page *pagination.Params) (*[]User, int64, bool, error) {
 	var err error
+	var total int64
 	users := []User{}
-	err = db.Debug().Model(&User{}).Limit(100).Find(&users).Error
 
+	err = filter.apply(db.Model(&User{})).Count(&total).Error
 	if err != nil {
-		return &[]User{}, err
+		return &[]User{}, 0, false, err
+	}
+
+	err = page.Scope(filter.apply(db.Model(&User{}))).Find(&users).Error
+	if err != nil {
+		return &[]User{}, 0, false, err
+	}
+
+	size, more, reverse := page.Window(len(users))
+	users = users[:size]
+	if reverse {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
 	}
 
-	return &users, nil
+	return &users, total, more, nil
 }
 
 func (u *User) FindUserByID(db *gorm.DB, uid uint64) (*User, error) {
 	var err error
-	err = db.Debug().Model(&User{}).Where("id = ?", uid).Take(&u).Error
+	err = db.Model(&User{}).Where("id = ?", uid).Take(&u).Error
 
 	if gorm.IsRecordNotFoundError(err) {
-		return &User{}, errors.New("User Not Found")
+		return &User{}, ErrUserNotFound
 	}
 
 	if err != nil {
@@ -141,44 +188,151 @@ func (u *User) FindUserByID(db *gorm.DB, uid uint64) (*User, error) {
 	return u, nil
 }
 
+// UpdateAUser saves the profile. Changing the email address clears verified_at so
+// the new address has to be confirmed before the policy treats it as verified.
 func (u *User) UpdateAUser(db *gorm.DB, uid int64) (*User, error) {
 	var err error
 
+	current := User{}
+	err = db.Model(&User{}).Where("id = ?", uid).Take(&current).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return &User{}, ErrUserNotFound
+	}
+	if err != nil {
+		return &User{}, err
+	}
+
 	// hash password
 	err = u.BeforeSave()
 	if err != nil {
-		log.Fatal(err)
 		return u, err
 	}
 
+	columns := map[string]interface{}{
+		"firstname":  u.Firstname,
+		"lastname":   u.Lastname,
+		"email":      u.Email,
+		"password":   u.Password,
+		"updated_at": time.Now(),
+	}
+	if u.Email != current.Email {
+		columns["verified_at"] = nil
+	}
+
 	// update the record
-	err = db.Debug().Model(&User{}).Where("id = ?", uid).Take(&u).UpdateColumns(
+	err = db.Model(&User{}).Where("id = ?", uid).UpdateColumns(columns).Error
+	if err != nil {
+		return &User{}, err
+	}
+
+	// retrieve the updated record
+	err = db.Model(&User{}).Where("id = ?", uid).Take(&u).Error
+
+	if err != nil {
+		return &User{}, err
+	}
+
+	return u, nil
+}
+
+func (u *User) FindUserByEmail(db *gorm.DB, email string) (*User, error) {
+	err := db.Model(&User{}).Where("email = ?", email).Take(&u).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return &User{}, ErrUserNotFound
+	}
+
+	if err != nil {
+		return &User{}, err
+	}
+
+	return u, nil
+}
+
+func (u *User) UpdatePassword(db *gorm.DB, uid int64, password string) error {
+	hashedPassword, err := Hash(password)
+	if err != nil {
+		return err
+	}
+
+	return db.Model(&User{}).Where("id = ?", uid).UpdateColumns(
 		map[string]interface{}{
-			"firstname": u.Firstname,
-			"lastname":  u.Lastname,
-			"email":     u.Email,
-			"password":  u.Password,
-			"updatedAt": time.Now(),
+			"password":   string(hashedPassword),
+			"updated_at": time.Now(),
 		},
 	).Error
+}
 
+func (u *User) IsVerified() bool {
+	return u.VerifiedAt != nil
+}
+
+func (u *User) MarkVerified(db *gorm.DB, uid int64) error {
+	return db.Model(&User{}).Where("id = ? AND verified_at IS NULL", uid).UpdateColumns(
+		map[string]interface{}{
+			"verified_at": time.Now(),
+			"updated_at":  time.Now(),
+		},
+	).Error
+}
+
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
+// Disable blocks the account from signing in and revokes its sessions.
+func (u *User) Disable(db *gorm.DB, uid int64) error {
+	err := db.Model(&User{}).Where("id = ? AND disabled_at IS NULL", uid).UpdateColumns(
+		map[string]interface{}{
+			"disabled_at": time.Now(),
+			"updated_at":  time.Now(),
+		},
+	).Error
+	if err != nil {
+		return err
+	}
+
+	return RevokeUserSessions(db, int(uid))
+}
+
+// UpdateRole changes the role and, when it differs, revokes the user's sessions: the
+// role is carried in access tokens, so they have to sign in again to get the new one.
+func (u *User) UpdateRole(db *gorm.DB, uid int64, role string) (*User, error) {
+	if !ValidRole(role) {
+		return &User{}, apperror.Invalid("role", "invalid_role", "Role Invalid")
+	}
+
+	_, err := u.FindUserByID(db, uint64(uid))
 	if err != nil {
 		return &User{}, err
 	}
+	if u.Role == role {
+		return u, nil
+	}
 
-	// retrieve the updated record
-	err = db.Debug().Model(&User{}).Where("id = ?", uid).Take(&u).Error
+	err = db.Model(&User{}).Where("id = ?", uid).UpdateColumns(
+		map[string]interface{}{
+			"role":       role,
+			"updated_at": time.Now(),
+		},
+	).Error
+	if err != nil {
+		return &User{}, err
+	}
 
+	err = RevokeUserSessions(db, int(uid))
 	if err != nil {
 		return &User{}, err
 	}
 
-	return u, nil
+	return u.FindUserByID(db, uint64(uid))
 }
 
 func (u *User) DeleteAUser(db *gorm.DB, uid int64) (int64, error) {
 
-	db = db.Debug().Model(&User{}).Where("id = ?", uid).Take(&u).Delete(&u)
+	db = db.Model(&User{}).Where("id = ?", uid).Take(&u).Delete(&u)
+	if gorm.IsRecordNotFoundError(db.Error) {
+		return 0, ErrUserNotFound
+	}
 	if db.Error != nil {
 		return 0, db.Error
 	}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+)
+
+// UserToken is a hashed, expiring, single-use token emailed to a user.
+type UserToken struct {
+	ID        int        `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    int        `gorm:"not null;index" json:"user_id"`
+	Purpose   string     `gorm:"size:32;not null" json:"purpose"`
+	TokenHash string     `gorm:"size:64;not null;unique" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+func (t *UserToken) IsUsable() bool {
+	return t.UsedAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
+func (t *UserToken) SaveUserToken(db *gorm.DB) (*UserToken, error) {
+	err := db.Model(&UserToken{}).Create(&t).Error
+	if err != nil {
+		return &UserToken{}, err
+	}
+
+	return t, nil
+}
+
+func (t *UserToken) FindUserTokenByHash(db *gorm.DB, purpose, hash string) (*UserToken, error) {
+	err := db.Model(&UserToken{}).Where("purpose = ? AND token_hash = ?", purpose, hash).Take(&t).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return &UserToken{}, ErrTokenNotFound
+	}
+
+	if err != nil {
+		return &UserToken{}, err
+	}
+
+	return t, nil
+}
+
+// Consume marks the token as used. It reports false when the token was already used.
+func (t *UserToken) Consume(db *gorm.DB) (bool, error) {
+	now := time.Now()
+	db = db.Model(&UserToken{}).Where("id = ? AND used_at IS NULL", t.ID).UpdateColumn("used_at", now)
+	if db.Error != nil {
+		return false, db.Error
+	}
+
+	if db.RowsAffected == 0 {
+		return false, nil
+	}
+
+	t.UsedAt = &now
+	return true, nil
+}
+
+// InvalidateUserTokens consumes every outstanding token of a purpose for the user.
+func InvalidateUserTokens(db *gorm.DB, userID int, purpose string) error {
+	return db.Model(&UserToken{}).Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		UpdateColumn("used_at", time.Now()).Error
+}
@@ -0,0 +1,18 @@
+package models
+
+import "github.com/stylll/GoBlog/api/apperror"
+
+var (
+	ErrUserNotFound         = apperror.NotFound("user_not_found", "User Not Found")
+	ErrPostNotFound         = apperror.NotFound("post_not_found", "Post Not Found")
+	ErrRevisionNotFound     = apperror.NotFound("revision_not_found", "Revision Not Found")
+	ErrTagNotFound          = apperror.NotFound("tag_not_found", "Tag Not Found")
+	ErrCategoryNotFound     = apperror.NotFound("category_not_found", "Category Not Found")
+	ErrSessionNotFound      = apperror.NotFound("session_not_found", "Session Not Found")
+	ErrTokenNotFound        = apperror.NotFound("token_not_found", "Token Not Found")
+	ErrRefreshTokenNotFound = apperror.NotFound("refresh_token_not_found", "Refresh Token Not Found")
+
+	ErrPostAlreadyPublished = apperror.Conflict("already_published", "Post already published")
+	ErrPostStatusUnchanged  = apperror.Conflict("status_unchanged", "Post already has this status")
+	ErrCategoryNotEmpty     = apperror.Conflict("category_not_empty", "Category still has subcategories")
+)
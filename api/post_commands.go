@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/stylll/GoBlog/api/models"
+)
+
+func postCommand(args []string) error {
+	name, args, err := subcommand("post", args)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "export":
+		return exportPosts(args)
+	case "import":
+		return importPosts(args)
+	default:
+		return fmt.Errorf("unknown post command %q", name)
+	}
+}
+
+func exportPosts(args []string) error {
+	flags, loader := newFlagSet("post export")
+	out := flags.String("out", "", "file to write to (default stdout)")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	err = initialize(loader)
+	if err != nil {
+		return err
+	}
+	defer server.DB.Close()
+
+	exported, err := models.ExportPosts(server.DB)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(exported)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d posts\n", len(exported))
+	return nil
+}
+
+func importPosts(args []string) error {
+	flags, loader := newFlagSet("post import")
+	in := flags.String("in", "", "file to read from (default stdin)")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		file, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	exported := []models.ExportedPost{}
+	err = json.NewDecoder(r).Decode(&exported)
+	if err != nil {
+		return fmt.Errorf("cannot read posts: %v", err)
+	}
+
+	err = initialize(loader)
+	if err != nil {
+		return err
+	}
+	defer server.DB.Close()
+
+	tx := server.DB.Begin()
+	defer tx.RollbackUnlessCommitted()
+
+	created, skipped, err := models.ImportPosts(tx, exported)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "imported %d posts, skipped %d with existing titles\n", created, skipped)
+	return nil
+}
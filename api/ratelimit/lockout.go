@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"strings"
+	"time"
+)
+
+// LockedError is returned while an account is locked out.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return "Too many failed login attempts, try again later"
+}
+
+// Lockout locks an account after Threshold consecutive failed logins. The first lock
+// lasts Base and every further failure doubles it, up to Max. Failures are forgotten
+// after a successful login or a day without failures. Its state lives in Store, so
+// replicas sharing a store share their lockouts.
+type Lockout struct {
+	Store     Store
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+}
+
+const lockoutMemory = time.Hour * 24
+
+func NewLockout(store Store, threshold int, base, max time.Duration) *Lockout {
+	return &Lockout{
+		Store:     store,
+		Threshold: threshold,
+		Base:      base,
+		Max:       max,
+	}
+}
+
+// Check returns a LockedError if account is currently locked. A nil Lockout never locks.
+func (l *Lockout) Check(account string) error {
+	if l == nil {
+		return nil
+	}
+
+	remaining, err := l.Store.LockedFor(lockoutKey(account))
+	if err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return &LockedError{RetryAfter: remaining}
+	}
+
+	return nil
+}
+
+// Fail records a failed login and returns a LockedError if the account is now locked.
+func (l *Lockout) Fail(account string) error {
+	if l == nil {
+		return nil
+	}
+
+	key := lockoutKey(account)
+	failures, err := l.Store.Fail(key, lockoutMemory)
+	if err != nil {
+		return err
+	}
+	if failures < l.Threshold {
+		return nil
+	}
+
+	duration := l.Base
+	for i := l.Threshold; i < failures && duration < l.Max; i++ {
+		duration *= 2
+	}
+	if duration > l.Max {
+		duration = l.Max
+	}
+
+	err = l.Store.Lock(key, duration)
+	if err != nil {
+		return err
+	}
+
+	return &LockedError{RetryAfter: duration}
+}
+
+// Succeed clears the failures of account.
+func (l *Lockout) Succeed(account string) error {
+	if l == nil {
+		return nil
+	}
+
+	return l.Store.Reset(lockoutKey(account))
+}
+
+func lockoutKey(account string) string {
+	return "lockout:" + strings.ToLower(strings.TrimSpace(account))
+}
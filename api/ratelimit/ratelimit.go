@@ -0,0 +1,283 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit allows Requests per Window, refilled continuously, with bursts of up to Requests.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// ParseLimit reads limits written as "10/1m" or "100/1h".
+func ParseLimit(s string) (Limit, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("rate limit %q must look like 10/1m", s)
+	}
+
+	requests, err := strconv.Atoi(parts[0])
+	if err != nil || requests < 1 {
+		return Limit{}, fmt.Errorf("rate limit %q must allow at least one request", s)
+	}
+
+	window, err := time.ParseDuration(parts[1])
+	if err != nil || window <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q has an invalid window", s)
+	}
+
+	return Limit{Requests: requests, Window: window}, nil
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+// Result describes the state of a bucket after a request has been counted.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request is allowed, zero if allowed
+}
+
+// Store keeps token buckets and the failure counters behind Lockout. MemoryStore
+// serves a single instance; shared backends such as Redis implement the same
+// interface so that replicas share their counts.
+type Store interface {
+	Take(key string, limit Limit) (Result, error)
+
+	// Fail counts a failure against key and returns the failures so far. The count
+	// starts over once memory has passed without a failure.
+	Fail(key string, memory time.Duration) (int, error)
+	// Lock locks key for d; LockedFor reports how much of the lock is left.
+	Lock(key string, d time.Duration) error
+	LockedFor(key string) (time.Duration, error)
+	// Reset forgets the failures and lock of key.
+	Reset(key string) error
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	window  time.Duration
+}
+
+type counter struct {
+	count   int
+	expires time.Time
+}
+
+// MemoryStore keeps buckets, counters and locks in process memory and drops full
+// buckets and expired entries as it goes.
+type MemoryStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	counters map[string]*counter
+	locks    map[string]time.Time
+	now      func() time.Time
+	swept    time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:  map[string]*bucket{},
+		counters: map[string]*counter{},
+		locks:    map[string]time.Time{},
+		now:      time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	capacity := float64(limit.Requests)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*limit.rate())
+	b.updated = now
+	b.window = limit.Window
+
+	result := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / limit.rate())
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((capacity - b.tokens) / limit.rate())
+
+	return result, nil
+}
+
+func (s *MemoryStore) Fail(key string, memory time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	c, ok := s.counters[key]
+	if !ok || !now.Before(c.expires) {
+		c = &counter{}
+		s.counters[key] = c
+	}
+	c.count++
+	c.expires = now.Add(memory)
+
+	return c.count, nil
+}
+
+func (s *MemoryStore) Lock(key string, d time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.locks[key] = s.now().Add(d)
+	return nil
+}
+
+func (s *MemoryStore) LockedFor(key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	until, ok := s.locks[key]
+	if !ok {
+		return 0, nil
+	}
+
+	remaining := until.Sub(s.now())
+	if remaining <= 0 {
+		return 0, nil
+	}
+	return remaining, nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.counters, key)
+	delete(s.locks, key)
+	return nil
+}
+
+// sweep forgets buckets that have refilled completely and expired counters and
+// locks, at most once a minute.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < time.Minute {
+		return
+	}
+	s.swept = now
+
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) > b.window {
+			delete(s.buckets, key)
+		}
+	}
+	for key, c := range s.counters {
+		if !now.Before(c.expires) {
+			delete(s.counters, key)
+		}
+	}
+	for key, until := range s.locks {
+		if !now.Before(until) {
+			delete(s.locks, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// KeyFunc picks the bucket a request is counted against.
+type KeyFunc func(r *http.Request) string
+
+// ByIP counts requests per client address. With trustProxy the first address in
+// X-Forwarded-For is used, which is only safe behind a proxy that sets it.
+func ByIP(trustProxy bool) KeyFunc {
+	return func(r *http.Request) string {
+		return "ip:" + ClientIP(r, trustProxy)
+	}
+}
+
+// ByRoute counts all requests to a route together.
+func ByRoute(route string) KeyFunc {
+	return func(r *http.Request) string {
+		return "route:" + route
+	}
+}
+
+// ByUser counts requests per signed-in user, using userID to read the caller from the
+// request context, and falls back to fallback for anonymous requests.
+func ByUser(userID func(ctx context.Context) int64, fallback KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		id := userID(r.Context())
+		if id == 0 {
+			return fallback(r)
+		}
+		return "user:" + strconv.FormatInt(id, 10)
+	}
+}
+
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Limiter applies one limit to requests grouped by Key. Name keeps the buckets of
+// different limiters apart when they share a store.
+type Limiter struct {
+	Name  string
+	Store Store
+	Limit Limit
+	Key   KeyFunc
+}
+
+func (l *Limiter) Allow(r *http.Request) (Result, error) {
+	return l.Store.Take(l.Name+":"+l.Key(r), l.Limit)
+}
+
+// WriteHeaders describes result in X-RateLimit-* headers, adding Retry-After when
+// the request was refused.
+func WriteHeaders(h http.Header, result Result) {
+	h.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	h.Set("X-RateLimit-Reset", RetryAfter(result.Reset))
+	if !result.Allowed {
+		h.Set("Retry-After", RetryAfter(result.RetryAfter))
+	}
+}
+
+// RetryAfter formats d as whole seconds, rounded up, for the Retry-After header.
+func RetryAfter(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -4,6 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/stylll/GoBlog/api/apperror"
+	"github.com/stylll/GoBlog/api/logger"
 )
 
 func JSON(w http.ResponseWriter, statusCode int, data interface{}) {
@@ -14,14 +18,78 @@ func JSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	}
 }
 
+// problem is an RFC 7807 problem document. Code is stable and meant for programs;
+// Detail is meant for people.
+type problem struct {
+	Type     string                `json:"type"`
+	Title    string                `json:"title"`
+	Status   int                   `json:"status"`
+	Detail   string                `json:"detail,omitempty"`
+	Instance string                `json:"instance,omitempty"`
+	Code     string                `json:"code"`
+	Errors   []apperror.FieldError `json:"errors,omitempty"`
+}
+
+// ERROR writes err as a problem document with the given status.
 func ERROR(w http.ResponseWriter, statusCode int, err error) {
-	if err != nil {
-		JSON(w, statusCode, struct {
-			Error string `json:"error"`
-		}{
-			Error: err.Error(),
-		})
-		return
+	if err == nil {
+		statusCode = http.StatusBadRequest
+	}
+
+	body := problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Code:   codeForStatus(statusCode),
+	}
+
+	if appErr, ok := err.(*apperror.Error); ok {
+		body.Code = appErr.Code
+		body.Detail = appErr.Message
+		body.Errors = appErr.Fields
+	} else if err != nil {
+		body.Detail = err.Error()
+	}
+
+	writeProblem(w, body)
+}
+
+// Problem writes err with the status its kind maps to. Errors that are not domain errors
+// are logged and reported as a generic internal error.
+func Problem(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := apperror.From(err)
+	status := appErr.Kind.Status()
+
+	if status == http.StatusInternalServerError {
+		cause := err
+		if appErr.Err != nil {
+			cause = appErr.Err
+		}
+		logger.FromContext(r.Context()).Error("Request failed", "error", cause)
+	}
+
+	writeProblem(w, problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   appErr.Message,
+		Instance: r.URL.Path,
+		Code:     appErr.Code,
+		Errors:   appErr.Fields,
+	})
+}
+
+func writeProblem(w http.ResponseWriter, body problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	JSON(w, body.Status, body)
+}
+
+// codeForStatus turns a status into a code, e.g. 404 into "not_found".
+func codeForStatus(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
 	}
-	JSON(w, http.StatusBadRequest, nil)
+
+	return strings.Replace(strings.ToLower(text), " ", "_", -1)
 }
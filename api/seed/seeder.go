@@ -1,24 +1,29 @@
 package seed
 
 import (
-	"log"
+	"fmt"
+	"time"
 
 	"github.com/jinzhu/gorm"
 	"github.com/stylll/GoBlog/api/models"
 )
 
+var verifiedAt = time.Now()
+
 var users = []models.User{
 	models.User{
-		Email:     "john@yah.com",
-		Firstname: "John",
-		Lastname:  "Andrew",
-		Password:  "JAndrew",
+		Email:      "john@yah.com",
+		Firstname:  "John",
+		Lastname:   "Andrew",
+		Password:   "JAndrew",
+		VerifiedAt: &verifiedAt,
 	},
 	models.User{
-		Email:     "mark@yah.com",
-		Firstname: "Mark",
-		Lastname:  "Donalds",
-		Password:  "MDonalds",
+		Email:      "mark@yah.com",
+		Firstname:  "Mark",
+		Lastname:   "Donalds",
+		Password:   "MDonalds",
+		VerifiedAt: &verifiedAt,
 	},
 }
 
@@ -26,39 +31,32 @@ var posts = []models.Post{
 	models.Post{
 		Title:   "My Adventure",
 		Content: "I travelled the world in 60 days",
+		Status:  models.PostStatusPublished,
 	},
 	models.Post{
 		Title:   "How to Write Code",
 		Content: "Software programming is an interesting profession",
+		Status:  models.PostStatusPublished,
 	},
 }
 
-func Load(db *gorm.DB) {
-	err := db.Debug().DropTableIfExists(&models.Post{}, &models.User{}).Error
-	if err != nil {
-		log.Fatalf("Cannot drop table: %v", err)
-	}
-
-	err = db.Debug().AutoMigrate(&models.User{}, &models.Post{}).Error
-	if err != nil {
-		log.Fatalf("Cannot migrate table: %v", err)
-	}
-
-	err = db.Debug().Model(&models.Post{}).AddForeignKey("author_id", "users(id)", "cascade", "cascade").Error
-	if err != nil {
-		log.Fatalf("Attaching foreign key error: %v", err)
-	}
-
+// Load inserts the sample users and posts. The schema must already be migrated;
+// records that already exist are left untouched, so it is safe to run twice.
+// The sample passwords are public, so the users are plain authors; create admins
+// with the user command.
+func Load(db *gorm.DB) error {
 	for i, _ := range users {
-		err = db.Debug().Model(&models.User{}).Create(&users[i]).Error
+		err := db.Model(&models.User{}).Where("email = ?", users[i].Email).FirstOrCreate(&users[i]).Error
 		if err != nil {
-			log.Fatalf("Cannot seed user table: %v", err)
+			return fmt.Errorf("Cannot seed user table: %v", err)
 		}
 		posts[i].AuthorID = users[i].ID
 
-		err = db.Debug().Model(&models.Post{}).Create(&posts[i]).Error
+		err = db.Model(&models.Post{}).Where("title = ?", posts[i].Title).FirstOrCreate(&posts[i]).Error
 		if err != nil {
-			log.Fatalf("Cannot seed post table: %v", err)
+			return fmt.Errorf("Cannot seed post table: %v", err)
 		}
 	}
+
+	return nil
 }
@@ -1,29 +1,33 @@
 package api
 
 import (
-	"fmt"
-	"log"
-	"os"
-
-	"github.com/joho/godotenv"
+	"github.com/stylll/GoBlog/api/config"
 	"github.com/stylll/GoBlog/api/controllers"
-	"github.com/stylll/GoBlog/api/seed"
 )
 
 var server = controllers.Server{}
 
-func Run() {
-	var err error
-	err = godotenv.Load()
+// initialize loads the configuration and connects to the database. Every command goes through it.
+func initialize(loader *config.Loader) error {
+	cfg, err := loader.Load()
 	if err != nil {
-		log.Fatalf("Error getting env variables: %v", err)
-	} else {
-		fmt.Println("Getting env variables")
+		return err
 	}
 
-	server.Initialize(os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_PORT"), os.Getenv("DB_HOST"), os.Getenv("DB_NAME"))
+	return server.Initialize(cfg)
+}
 
-	seed.Load(server.DB)
+func serve(args []string) error {
+	flags, loader := newFlagSet("serve")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	err = initialize(loader)
+	if err != nil {
+		return err
+	}
 
-	server.Run(":8080")
+	return server.Run()
 }
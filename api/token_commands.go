@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/stylll/GoBlog/api/models"
+)
+
+func tokenCommand(args []string) error {
+	name, args, err := subcommand("token", args)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "issue":
+		return issueToken(args)
+	default:
+		return fmt.Errorf("unknown token command %q", name)
+	}
+}
+
+func issueToken(args []string) error {
+	flags, loader := newFlagSet("token issue")
+	email := flags.String("email", "", "email address of the user")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	err = required(flags, "email")
+	if err != nil {
+		return err
+	}
+
+	err = initialize(loader)
+	if err != nil {
+		return err
+	}
+	defer server.DB.Close()
+
+	user := models.User{}
+	_, err = user.FindUserByEmail(server.DB, *email)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := server.StartSession(server.DB, &user)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(tokens)
+}
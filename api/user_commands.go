@@ -0,0 +1,145 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/stylll/GoBlog/api/models"
+)
+
+func userCommand(args []string) error {
+	name, args, err := subcommand("user", args)
+	if err != nil {
+		return err
+	}
+
+	switch name {
+	case "create":
+		return createUser(args)
+	case "promote":
+		return promoteUser(args)
+	case "disable":
+		return disableUser(args)
+	default:
+		return fmt.Errorf("unknown user command %q", name)
+	}
+}
+
+func createUser(args []string) error {
+	flags, loader := newFlagSet("user create")
+	email := flags.String("email", "", "email address")
+	firstname := flags.String("firstname", "", "first name")
+	lastname := flags.String("lastname", "", "last name")
+	password := flags.String("password", "", "password")
+	role := flags.String("role", models.RoleAuthor, "admin, editor or author")
+	verified := flags.Bool("verified", false, "mark the email address as verified")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if !models.ValidRole(*role) {
+		return fmt.Errorf("invalid role %q", *role)
+	}
+
+	user := models.User{
+		Firstname: *firstname,
+		Lastname:  *lastname,
+		Email:     *email,
+		Password:  *password,
+	}
+	user.Prepare()
+	err = user.Validate("")
+	if err != nil {
+		return err
+	}
+
+	user.Role = *role
+	if *verified {
+		now := time.Now()
+		user.VerifiedAt = &now
+	}
+
+	err = initialize(loader)
+	if err != nil {
+		return err
+	}
+	defer server.DB.Close()
+
+	_, err = user.SaveUser(server.DB)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created user %d <%s> with role %s\n", user.ID, user.Email, user.Role)
+	return nil
+}
+
+func promoteUser(args []string) error {
+	flags, loader := newFlagSet("user promote")
+	email := flags.String("email", "", "email address")
+	role := flags.String("role", "", "admin, editor or author")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	err = required(flags, "email", "role")
+	if err != nil {
+		return err
+	}
+
+	err = initialize(loader)
+	if err != nil {
+		return err
+	}
+	defer server.DB.Close()
+
+	user := models.User{}
+	_, err = user.FindUserByEmail(server.DB, *email)
+	if err != nil {
+		return err
+	}
+
+	_, err = user.UpdateRole(server.DB, int64(user.ID), *role)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("user %d <%s> is now %s; their sessions were revoked so the new role applies when they sign in again\n", user.ID, user.Email, *role)
+	return nil
+}
+
+func disableUser(args []string) error {
+	flags, loader := newFlagSet("user disable")
+	email := flags.String("email", "", "email address")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	err = required(flags, "email")
+	if err != nil {
+		return err
+	}
+
+	err = initialize(loader)
+	if err != nil {
+		return err
+	}
+	defer server.DB.Close()
+
+	user := models.User{}
+	_, err = user.FindUserByEmail(server.DB, *email)
+	if err != nil {
+		return err
+	}
+
+	err = user.Disable(server.DB, int64(user.ID))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("disabled user %d <%s> and revoked their sessions\n", user.ID, user.Email)
+	return nil
+}
@@ -0,0 +1,106 @@
+package diff
+
+import (
+	"strings"
+
+	"github.com/stylll/GoBlog/api/apperror"
+)
+
+const (
+	Equal  = "equal"
+	Insert = "insert"
+	Delete = "delete"
+)
+
+// Line is one line of a diff: kept, added in the newer text or removed from the older.
+type Line struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// MaxCells bounds the lines compared after the common start and end are set aside:
+// the table takes rows times columns cells, about 16MB at this size.
+const MaxCells = 1 << 22
+
+var ErrTooLarge = apperror.New(apperror.KindValidation, "diff_too_large",
+	"The revisions differ in too many lines to compare")
+
+// Lines compares a and b line by line and returns the edit script turning a into b,
+// based on their longest common subsequence. Deletions come before insertions where
+// a line was replaced. Lines the texts share at the start and end are matched
+// directly; ErrTooLarge is returned when what is left would need more than MaxCells.
+func Lines(a, b string) ([]Line, error) {
+	x, y := split(a), split(b)
+
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(x)+len(y)-prefix-suffix)
+	for _, text := range x[:prefix] {
+		lines = append(lines, Line{Op: Equal, Text: text})
+	}
+	tail := x[len(x)-suffix:]
+	x, y = x[prefix:len(x)-suffix], y[prefix:len(y)-suffix]
+
+	if (len(x)+1)*(len(y)+1) > MaxCells {
+		return nil, ErrTooLarge
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int32, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			lines = append(lines, Line{Op: Equal, Text: x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Op: Delete, Text: x[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, Text: y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		lines = append(lines, Line{Op: Delete, Text: x[i]})
+	}
+	for ; j < len(y); j++ {
+		lines = append(lines, Line{Op: Insert, Text: y[j]})
+	}
+	for _, text := range tail {
+		lines = append(lines, Line{Op: Equal, Text: text})
+	}
+
+	return lines, nil
+}
+
+func split(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+}
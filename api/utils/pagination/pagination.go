@@ -0,0 +1,232 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stylll/GoBlog/api/apperror"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+
+	Asc  = "asc"
+	Desc = "desc"
+
+	forward  = "next"
+	backward = "prev"
+)
+
+// Field is a sortable column. Time fields are carried in cursors as RFC 3339 timestamps.
+type Field struct {
+	Column       string
+	Time         bool
+	DefaultOrder string
+}
+
+// Key identifies a row's position in a sorted listing.
+type Key struct {
+	Value string
+	ID    int
+}
+
+type cursor struct {
+	Sort      string `json:"s"`
+	Order     string `json:"o"`
+	Value     string `json:"v"`
+	ID        int    `json:"id"`
+	Direction string `json:"d"`
+}
+
+type Params struct {
+	Limit int
+	Sort  string
+	Order string
+
+	field  Field
+	cursor *cursor
+	value  interface{}
+}
+
+type Links struct {
+	Next *string `json:"next"`
+	Prev *string `json:"prev"`
+}
+
+// Envelope is the response body of every paginated listing.
+type Envelope struct {
+	Data  interface{} `json:"data"`
+	Total int64       `json:"total"`
+	Limit int         `json:"limit"`
+	Links Links       `json:"links"`
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, apperror.BadRequest("invalid_cursor", "Cursor Invalid")
+	}
+
+	c := cursor{}
+	err = json.Unmarshal(b, &c)
+	if err != nil || (c.Direction != forward && c.Direction != backward) {
+		return nil, apperror.BadRequest("invalid_cursor", "Cursor Invalid")
+	}
+
+	return &c, nil
+}
+
+// Parse reads limit, sort, order and cursor from the query string.
+func Parse(query url.Values, fields map[string]Field, defaultSort string) (*Params, error) {
+	p := &Params{Limit: DefaultLimit, Sort: defaultSort}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return nil, apperror.BadRequest("invalid_limit", "Limit Invalid")
+		}
+		if n > MaxLimit {
+			n = MaxLimit
+		}
+		p.Limit = n
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		p.Sort = sort
+	}
+
+	field, ok := fields[p.Sort]
+	if !ok {
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		return nil, apperror.BadRequest("invalid_sort", "Sort must be one of "+strings.Join(names, ", "))
+	}
+	p.field = field
+
+	p.Order = strings.ToLower(query.Get("order"))
+	if p.Order == "" {
+		p.Order = field.DefaultOrder
+	}
+	if p.Order != Asc && p.Order != Desc {
+		return nil, apperror.BadRequest("invalid_order", "Order must be asc or desc")
+	}
+
+	if raw := query.Get("cursor"); raw != "" {
+		c, err := decodeCursor(raw)
+		if err != nil {
+			return nil, err
+		}
+		if c.Sort != p.Sort || c.Order != p.Order {
+			return nil, apperror.BadRequest("invalid_cursor", "Cursor does not match sort order")
+		}
+
+		p.cursor = c
+		p.value = c.Value
+		if field.Time {
+			t, err := time.Parse(time.RFC3339Nano, c.Value)
+			if err != nil {
+				return nil, apperror.BadRequest("invalid_cursor", "Cursor Invalid")
+			}
+			p.value = t
+		}
+	}
+
+	return p, nil
+}
+
+func (p *Params) backward() bool {
+	return p.cursor != nil && p.cursor.Direction == backward
+}
+
+// Scope applies the cursor condition, ordering and limit. One extra row is
+// fetched so Window can tell whether another page exists.
+func (p *Params) Scope(db *gorm.DB) *gorm.DB {
+	ascending := p.Order == Asc
+	if p.backward() {
+		ascending = !ascending
+	}
+
+	op, dir := "<", "DESC"
+	if ascending {
+		op, dir = ">", "ASC"
+	}
+
+	if p.cursor != nil {
+		condition := fmt.Sprintf("(%[1]s %[2]s ?) OR (%[1]s = ? AND id %[2]s ?)", p.field.Column, op)
+		db = db.Where(condition, p.value, p.value, p.cursor.ID)
+	}
+
+	return db.Order(fmt.Sprintf("%s %s, id %s", p.field.Column, dir, dir)).Limit(p.Limit + 1)
+}
+
+// Window reports how many of the fetched rows belong on the page, whether
+// more rows exist beyond it, and whether the rows must be reversed.
+func (p *Params) Window(fetched int) (size int, more bool, reverse bool) {
+	size = fetched
+	if fetched > p.Limit {
+		size = p.Limit
+		more = true
+	}
+
+	return size, more, p.backward()
+}
+
+// FormatValue renders a sort column value the way cursors carry it.
+func FormatValue(v interface{}) string {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+
+	return fmt.Sprint(v)
+}
+
+// Links builds next and prev URLs around the first and last rows of the page.
+func (p *Params) Links(u *url.URL, first, last *Key, more bool) Links {
+	links := Links{}
+	if first == nil || last == nil {
+		return links
+	}
+
+	hasNext, hasPrev := more, p.cursor != nil
+	if p.backward() {
+		hasNext, hasPrev = true, more
+	}
+
+	if hasNext {
+		link := p.link(u, cursor{Value: last.Value, ID: last.ID, Direction: forward})
+		links.Next = &link
+	}
+	if hasPrev {
+		link := p.link(u, cursor{Value: first.Value, ID: first.ID, Direction: backward})
+		links.Prev = &link
+	}
+
+	return links
+}
+
+func (p *Params) link(u *url.URL, c cursor) string {
+	c.Sort = p.Sort
+	c.Order = p.Order
+
+	query := u.Query()
+	query.Set("cursor", encodeCursor(c))
+	query.Set("limit", strconv.Itoa(p.Limit))
+	query.Set("sort", p.Sort)
+	query.Set("order", p.Order)
+
+	return u.Path + "?" + query.Encode()
+}
@@ -0,0 +1,107 @@
+package slug
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MaxLength leaves room for a numeric suffix within the 255 character column.
+const MaxLength = 80
+
+// Fallback is used for titles with nothing left to slug, e.g. only punctuation.
+const Fallback = "post"
+
+// transliterations spells letters outside ASCII in Latin letters. Accented Latin
+// letters that are not listed lose their accent through unaccented.
+var transliterations = map[rune]string{
+	'\'': "", '’': "",
+
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'ł': "l", 'þ': "th", 'ı': "i",
+
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+}
+
+// accents maps the accented Latin-1 and Latin Extended-A letters to their base letter.
+var accents = map[string]string{
+	"a": "àáâãäåāăą",
+	"c": "çćĉċč",
+	"d": "ď",
+	"e": "èéêëēĕėęě",
+	"g": "ĝğġģ",
+	"h": "ĥħ",
+	"i": "ìíîïĩīĭįİ",
+	"j": "ĵ",
+	"k": "ķ",
+	"l": "ĺļľŀ",
+	"n": "ñńņňŉ",
+	"o": "òóôõöōŏő",
+	"r": "ŕŗř",
+	"s": "śŝşš",
+	"t": "ţťŧ",
+	"u": "ùúûüũūŭůűų",
+	"w": "ŵ",
+	"y": "ýÿŷ",
+	"z": "źżž",
+}
+
+var unaccented = map[rune]string{}
+
+func init() {
+	for base, letters := range accents {
+		for _, r := range letters {
+			unaccented[r] = base
+		}
+	}
+}
+
+// Make turns text into a lowercase, hyphen-separated ASCII slug such as
+// "creme-brulee-for-beginners". Letters without a transliteration are dropped.
+func Make(text string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(text) {
+		latin, ok := transliterations[r]
+		if !ok {
+			latin, ok = unaccented[r]
+		}
+		if !ok && r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			latin, ok = string(r), true
+		}
+
+		if !ok {
+			// anything else separates words, but letters we cannot spell do not
+			hyphen = hyphen || !unicode.IsLetter(r)
+			continue
+		}
+		if latin == "" {
+			continue
+		}
+		if hyphen && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		hyphen = false
+		b.WriteString(latin)
+	}
+
+	slug := b.String()
+	if len(slug) > MaxLength {
+		slug = slug[:MaxLength]
+		if cut := strings.LastIndexByte(slug, '-'); cut > 0 {
+			slug = slug[:cut]
+		}
+	}
+	if slug == "" {
+		return Fallback
+	}
+
+	return slug
+}
@@ -0,0 +1,135 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/badoux/checkmail"
+	"github.com/stylll/GoBlog/api/apperror"
+)
+
+// Rule checks a value and returns the code and message of the violation, or an empty
+// code when the value passes. label names the field in messages, e.g. "Email".
+type Rule func(label, value string) (code, message string)
+
+// Validator collects the violations of every checked field.
+type Validator struct {
+	fields []apperror.FieldError
+}
+
+func New() *Validator {
+	return &Validator{}
+}
+
+// Field checks value against rules in order and records the first violation, so each
+// field is reported at most once.
+func (v *Validator) Field(path, label, value string, rules ...Rule) *Validator {
+	for _, rule := range rules {
+		if code, message := rule(label, value); code != "" {
+			v.Add(path, code, message)
+			break
+		}
+	}
+
+	return v
+}
+
+// Add records a violation found outside of the rules, e.g. by a database lookup.
+func (v *Validator) Add(path, code, message string) {
+	v.fields = append(v.fields, apperror.FieldError{Field: path, Code: code, Message: message})
+}
+
+// Err returns all recorded violations as one validation error, or nil if there are none.
+func (v *Validator) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+
+	return apperror.Validation(v.fields...)
+}
+
+func Required(label, value string) (string, string) {
+	if value == "" {
+		return "required", label + " Required"
+	}
+
+	return "", ""
+}
+
+// MinLength and MaxLength count characters, not bytes. Empty values are left to Required.
+func MinLength(n int) Rule {
+	return func(label, value string) (string, string) {
+		if value != "" && utf8.RuneCountInString(value) < n {
+			return "too_short", fmt.Sprintf("%s must be at least %d characters", label, n)
+		}
+
+		return "", ""
+	}
+}
+
+func MaxLength(n int) Rule {
+	return func(label, value string) (string, string) {
+		if utf8.RuneCountInString(value) > n {
+			return "too_long", fmt.Sprintf("%s must be at most %d characters", label, n)
+		}
+
+		return "", ""
+	}
+}
+
+// MaxBytes limits the encoded size of values stored with byte limits, such as passwords
+// hashed by bcrypt.
+func MaxBytes(n int) Rule {
+	return func(label, value string) (string, string) {
+		if len(value) > n {
+			return "too_long", fmt.Sprintf("%s must be at most %d bytes; accented letters and symbols take several", label, n)
+		}
+
+		return "", ""
+	}
+}
+
+// OneOf accepts the listed values. Empty values are left to Required.
+func OneOf(values ...string) Rule {
+	return func(label, value string) (string, string) {
+		if value == "" {
+			return "", ""
+		}
+		for _, allowed := range values {
+			if value == allowed {
+				return "", ""
+			}
+		}
+
+		return "invalid_choice", fmt.Sprintf("%s must be one of %s", label, strings.Join(values, ", "))
+	}
+}
+
+func Email(label, value string) (string, string) {
+	if value != "" && checkmail.ValidateFormat(value) != nil {
+		return "invalid_email", label + " Invalid"
+	}
+
+	return "", ""
+}
+
+// StrongPassword requires at least one letter and one digit; pair it with MinLength.
+func StrongPassword(label, value string) (string, string) {
+	var letter, digit bool
+	for _, r := range value {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+
+	if value != "" && !(letter && digit) {
+		return "weak_password", label + " must contain a letter and a digit"
+	}
+
+	return "", ""
+}
@@ -1,13 +1,27 @@
 module github.com/stylll/GoBlog
 
-go 1.12
+go 1.19
 
 require (
+	github.com/alecthomas/chroma/v2 v2.14.0
 	github.com/badoux/checkmail v0.0.0-20181210160741-9661bd69e9ad
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/gorilla/mux v1.7.3
 	github.com/jinzhu/gorm v1.9.11
 	github.com/joho/godotenv v1.3.0
-	golang.org/x/crypto v0.0.0-20191112222119-e1110fd1c708
+	github.com/lib/pq v1.1.1
+	github.com/microcosm-cc/bluemonday v1.0.21
+	github.com/yuin/goldmark v1.7.8
+	github.com/yuin/goldmark-highlighting/v2 v2.0.0-20230729083705-37449abec8cc
+	golang.org/x/crypto v0.14.0
 	gopkg.in/go-playground/assert.v1 v1.2.1
+	gopkg.in/yaml.v2 v2.4.0
+)
+
+require (
+	github.com/aymerick/douceur v0.2.0 // indirect
+	github.com/dlclark/regexp2 v1.11.0 // indirect
+	github.com/gorilla/css v1.0.0 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	golang.org/x/net v0.17.0 // indirect
 )
@@ -1,7 +1,15 @@
 package main
 
-import "github.com/stylll/GoBlog/api"
+import (
+	"log"
+	"os"
+
+	"github.com/stylll/GoBlog/api"
+)
 
 func main() {
-	api.Run()
+	err := api.Execute(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
 }
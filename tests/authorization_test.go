@@ -0,0 +1,225 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stylll/GoBlog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+type authzCase struct {
+	name   string
+	method string
+	path   string
+	token  string
+	body   string
+	status int
+}
+
+// seedUserWithRole creates a user with the role and returns it with an access token.
+func seedUserWithRole(firstname, role string) (models.User, string, error) {
+	user := models.User{
+		Firstname: firstname,
+		Lastname:  "Scott",
+		Email:     fmt.Sprintf("%s@dundermifflin.com", firstname),
+		Password:  "Paper2020",
+		Role:      role,
+	}
+
+	err := seedSingleUser(&user)
+	if err != nil {
+		return user, "", err
+	}
+
+	tokens, err := server.SignIn(server.DB, user.Email, "Paper2020")
+	if err != nil {
+		return user, "", err
+	}
+
+	return user, tokens.AccessToken, nil
+}
+
+func runAuthzCases(t *testing.T, cases []authzCase) {
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, bytes.NewBufferString(c.body))
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		rr := httptest.NewRecorder()
+		server.Router.ServeHTTP(rr, req)
+		if rr.Code != c.status {
+			t.Errorf("%s: %s %s returned %d, want %d: %s", c.name, c.method, c.path, rr.Code, c.status, rr.Body.String())
+		}
+	}
+}
+
+// serveJSON sends the request through the router, checks the status and decodes the
+// response into v.
+func serveJSON(t *testing.T, method, path, token, body string, status int, v interface{}) {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rr := httptest.NewRecorder()
+	server.Router.ServeHTTP(rr, req)
+	if rr.Code != status {
+		t.Fatalf("%s %s returned %d, want %d: %s", method, path, rr.Code, status, rr.Body.String())
+	}
+
+	err := json.Unmarshal(rr.Body.Bytes(), v)
+	if err != nil {
+		t.Errorf("Cannot convert response to json: %v", err)
+	}
+}
+
+func setupAuthz(t *testing.T) (author, other, editor, admin models.User, tokens map[string]string) {
+	for _, refresh := range []func() error{refreshUserTable, refreshPostTable, refreshSessionTables} {
+		err := refresh()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	server.InitializeRoutes()
+
+	tokens = map[string]string{}
+	var err error
+	seed := func(firstname, role string) models.User {
+		user, token, seedErr := seedUserWithRole(firstname, role)
+		if seedErr != nil {
+			err = seedErr
+		}
+		tokens[firstname] = token
+		return user
+	}
+
+	author = seed("jim", models.RoleAuthor)
+	other = seed("dwight", models.RoleAuthor)
+	editor = seed("angela", models.RoleEditor)
+	admin = seed("michael", models.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return author, other, editor, admin, tokens
+}
+
+func TestPostAuthorization(t *testing.T) {
+	author, _, _, _, tokens := setupAuthz(t)
+
+	posts := make([]models.Post, 4)
+	for i := range posts {
+		posts[i] = models.Post{Title: fmt.Sprintf("Memo %d", i), Content: "Please read", AuthorID: author.ID}
+		err := seedSinglePost(&posts[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	edited, deletedByAuthor, deletedByAdmin, kept := posts[0], posts[1], posts[2], posts[3]
+	path := func(p models.Post) string { return fmt.Sprintf("/posts/%d", p.ID) }
+	update := func(title string) string { return fmt.Sprintf(`{"title": "%s", "content": "Updated"}`, title) }
+
+	runAuthzCases(t, []authzCase{
+		{"create anonymous", "POST", "/posts", "", update("New"), http.StatusUnauthorized},
+		{"create invalid token", "POST", "/posts", "garbage", update("New"), http.StatusUnauthorized},
+
+		{"update anonymous", "PUT", path(edited), "", update("A"), http.StatusUnauthorized},
+		{"update other author", "PUT", path(edited), tokens["dwight"], update("B"), http.StatusForbidden},
+		{"update owner", "PUT", path(edited), tokens["jim"], update("C"), http.StatusOK},
+		{"update editor", "PUT", path(edited), tokens["angela"], update("D"), http.StatusOK},
+		{"update admin", "PUT", path(edited), tokens["michael"], update("E"), http.StatusOK},
+		{"update missing", "PUT", "/posts/999999", tokens["dwight"], update("F"), http.StatusNotFound},
+		{"update missing admin", "PUT", "/posts/999999", tokens["michael"], update("G"), http.StatusNotFound},
+		{"update bad id", "PUT", "/posts/abc", tokens["jim"], update("H"), http.StatusBadRequest},
+
+		{"delete anonymous", "DELETE", path(kept), "", "", http.StatusUnauthorized},
+		{"delete editor", "DELETE", path(kept), tokens["angela"], "", http.StatusForbidden},
+		{"delete other author", "DELETE", path(deletedByAuthor), tokens["dwight"], "", http.StatusForbidden},
+		{"delete owner", "DELETE", path(deletedByAuthor), tokens["jim"], "", http.StatusNoContent},
+		{"delete already deleted", "DELETE", path(deletedByAuthor), tokens["jim"], "", http.StatusNotFound},
+		{"delete admin", "DELETE", path(deletedByAdmin), tokens["michael"], "", http.StatusNoContent},
+		{"delete missing admin", "DELETE", "/posts/999999", tokens["michael"], "", http.StatusNotFound},
+		{"delete bad id admin", "DELETE", "/posts/abc", tokens["michael"], "", http.StatusBadRequest},
+	})
+
+	// refused requests must not have changed anything
+	post := models.Post{}
+	_, err := post.FindPostByID(server.DB, kept.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, post.Title, kept.Title)
+}
+
+func TestUserAuthorization(t *testing.T) {
+	author, other, editor, _, tokens := setupAuthz(t)
+
+	path := func(u models.User) string { return fmt.Sprintf("/users/%d", u.ID) }
+	update := func(u models.User, firstname string) string {
+		return fmt.Sprintf(`{"firstname": "%s", "lastname": "Halpert", "email": "%s", "password": "BigTuna22"}`, firstname, u.Email)
+	}
+	role := func(r string) string { return fmt.Sprintf(`{"role": "%s"}`, r) }
+
+	runAuthzCases(t, []authzCase{
+		{"update anonymous", "PUT", path(author), "", update(author, "Jim"), http.StatusUnauthorized},
+		{"update other user", "PUT", path(author), tokens["dwight"], update(author, "Dwight"), http.StatusForbidden},
+		{"update editor", "PUT", path(author), tokens["angela"], update(author, "Angela"), http.StatusForbidden},
+		{"update self", "PUT", path(author), tokens["jim"], update(author, "James"), http.StatusOK},
+		{"update admin", "PUT", path(author), tokens["michael"], update(author, "Jimmy"), http.StatusOK},
+		{"update missing", "PUT", "/users/999999", tokens["dwight"], update(author, "Nobody"), http.StatusNotFound},
+		{"update bad id", "PUT", "/users/abc", tokens["jim"], update(author, "Jim"), http.StatusBadRequest},
+
+		{"role anonymous", "PUT", path(other) + "/role", "", role(models.RoleEditor), http.StatusUnauthorized},
+		{"role self", "PUT", path(other) + "/role", tokens["dwight"], role(models.RoleAdmin), http.StatusForbidden},
+		{"role editor", "PUT", path(other) + "/role", tokens["angela"], role(models.RoleEditor), http.StatusForbidden},
+		{"role admin", "PUT", path(editor) + "/role", tokens["michael"], role(models.RoleAuthor), http.StatusOK},
+		{"role missing", "PUT", "/users/999999/role", tokens["michael"], role(models.RoleEditor), http.StatusNotFound},
+
+		{"delete anonymous", "DELETE", path(editor), "", "", http.StatusUnauthorized},
+		{"delete other user", "DELETE", path(editor), tokens["jim"], "", http.StatusForbidden},
+		{"delete missing", "DELETE", "/users/999999", tokens["jim"], "", http.StatusNotFound},
+		{"delete admin", "DELETE", path(editor), tokens["michael"], "", http.StatusNoContent},
+		{"delete self", "DELETE", path(other), tokens["dwight"], "", http.StatusNoContent},
+	})
+
+	user := models.User{}
+	_, err := user.FindUserByID(server.DB, uint64(author.ID))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, user.Firstname, "Jimmy")
+}
+
+func TestRoleChangeRevokesSessions(t *testing.T) {
+	_, _, _, admin, tokens := setupAuthz(t)
+
+	jan, _, err := seedUserWithRole("jan", models.RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := server.SignIn(server.DB, jan.Email, "Paper2020")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/users/%d/role", admin.ID)
+	role := func(r string) string { return fmt.Sprintf(`{"role": "%s"}`, r) }
+	refresh := fmt.Sprintf(`{"refresh_token": %q}`, before.RefreshToken)
+
+	// tokens issued before the demotion stop working, the refresh token included
+	runAuthzCases(t, []authzCase{
+		{"admin before demotion", "PUT", path, before.AccessToken, role(models.RoleAdmin), http.StatusOK},
+		{"demote", "PUT", fmt.Sprintf("/users/%d/role", jan.ID), tokens["michael"], role(models.RoleEditor), http.StatusOK},
+		{"token after demotion", "PUT", path, before.AccessToken, role(models.RoleAuthor), http.StatusUnauthorized},
+		{"refresh after demotion", "POST", "/token/refresh", "", refresh, http.StatusUnauthorized},
+	})
+
+	after, err := server.SignIn(server.DB, jan.Email, "Paper2020")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runAuthzCases(t, []authzCase{
+		{"demoted admin", "PUT", path, after.AccessToken, role(models.RoleAuthor), http.StatusForbidden},
+	})
+}
@@ -8,11 +8,15 @@ import (
 
 	"github.com/jinzhu/gorm"
 	"github.com/joho/godotenv"
+	"github.com/stylll/GoBlog/api/auth"
+	"github.com/stylll/GoBlog/api/config"
 	"github.com/stylll/GoBlog/api/controllers"
+	"github.com/stylll/GoBlog/api/mailer"
 	"github.com/stylll/GoBlog/api/models"
 )
 
-var server = controllers.Server{}
+var outbox = &mailer.MemoryMailer{}
+var server = controllers.Server{Mailer: outbox}
 var userInstance = models.User{}
 var postInstance = models.Post{}
 
@@ -22,6 +26,10 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Error getting env %v\n", err)
 	}
 
+	server.Config = config.Defaults()
+	server.Config.Auth.Secret = os.Getenv("API_SECRET")
+	auth.Configure(server.Config.Auth)
+
 	setupDatabase()
 
 	os.Exit(m.Run())
@@ -58,12 +66,12 @@ func refreshUserTable() error {
 }
 
 func refreshPostTable() error {
-	err := server.DB.DropTableIfExists(&models.Post{}).Error
+	err := server.DB.DropTableIfExists(&models.PostTag{}, &models.Tag{}, &models.PostSlug{}, &models.PostRevision{}, &models.Post{}, &models.Category{}).Error
 	if err != nil {
 		return err
 	}
 
-	err = server.DB.AutoMigrate(&models.Post{}).Error
+	err = server.DB.AutoMigrate(&models.Category{}, &models.Post{}, &models.PostRevision{}, &models.PostSlug{}, &models.Tag{}, &models.PostTag{}).Error
 	if err != nil {
 		return err
 	}
@@ -73,8 +81,40 @@ func refreshPostTable() error {
 	return nil
 }
 
+func refreshSessionTables() error {
+	err := server.DB.DropTableIfExists(&models.RefreshToken{}, &models.Session{}).Error
+	if err != nil {
+		return err
+	}
+
+	err = server.DB.AutoMigrate(&models.Session{}, &models.RefreshToken{}).Error
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Session tables refreshed successfully")
+
+	return nil
+}
+
+func refreshUserTokenTable() error {
+	err := server.DB.DropTableIfExists(&models.UserToken{}).Error
+	if err != nil {
+		return err
+	}
+
+	err = server.DB.AutoMigrate(&models.UserToken{}).Error
+	if err != nil {
+		return err
+	}
+
+	log.Printf("User tokens table refreshed successfully")
+
+	return nil
+}
+
 func seedSingleUser(user *models.User) error {
-	err := server.DB.Debug().Model(&models.User{}).Create(user).Error
+	err := server.DB.Model(&models.User{}).Create(user).Error
 	if err != nil {
 		return err
 	}
@@ -83,7 +123,7 @@ func seedSingleUser(user *models.User) error {
 }
 
 func seedSinglePost(post *models.Post) error {
-	err := server.DB.Debug().Model(&models.Post{}).Create(post).Error
+	err := server.DB.Model(&models.Post{}).Create(post).Error
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stylll/GoBlog/api/config"
+	"github.com/stylll/GoBlog/api/middlewares"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func corsHandler() http.Handler {
+	router := mux.NewRouter()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	router.HandleFunc("/posts", ok).Methods("GET")
+	router.HandleFunc("/posts", ok).Methods("POST")
+	router.HandleFunc("/posts/{id}", ok).Methods("PUT")
+
+	cfg := config.Defaults().CORS
+	cfg.AllowedOrigins = []string{"https://app.example.com"}
+	cfg.AllowCredentials = true
+	cfg.MaxAge = time.Minute
+
+	return middlewares.SetMiddlewareCORS(cfg, router, router)
+}
+
+func preflight(origin, path, method, headers string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("OPTIONS", path, nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		req.Header.Set("Access-Control-Request-Headers", headers)
+	}
+
+	rr := httptest.NewRecorder()
+	corsHandler().ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCORSPreflight(t *testing.T) {
+	rr := preflight("https://app.example.com", "/posts", "POST", "authorization, content-type")
+	assert.Equal(t, rr.Code, http.StatusNoContent)
+	assert.Equal(t, rr.Header().Get("Access-Control-Allow-Origin"), "https://app.example.com")
+	assert.Equal(t, rr.Header().Get("Access-Control-Allow-Credentials"), "true")
+	assert.Equal(t, rr.Header().Get("Access-Control-Allow-Headers"), "Authorization, Content-Type")
+	assert.Equal(t, rr.Header().Get("Access-Control-Max-Age"), "60")
+
+	rr = preflight("https://app.example.com", "/posts/1", "PUT", "")
+	assert.Equal(t, rr.Code, http.StatusNoContent)
+
+	rr = preflight("https://app.example.com", "/posts/1", "DELETE", "")
+	assert.Equal(t, rr.Code, http.StatusMethodNotAllowed)
+
+	rr = preflight("https://app.example.com", "/nowhere", "GET", "")
+	assert.Equal(t, rr.Code, http.StatusNotFound)
+
+	rr = preflight("https://evil.example.com", "/posts", "POST", "")
+	assert.Equal(t, rr.Code, http.StatusForbidden)
+	assert.Equal(t, rr.Header().Get("Access-Control-Allow-Origin"), "")
+
+	rr = preflight("https://app.example.com", "/posts", "POST", "X-Secret")
+	assert.Equal(t, rr.Code, http.StatusForbidden)
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	req := httptest.NewRequest("GET", "/posts", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rr := httptest.NewRecorder()
+	corsHandler().ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, rr.Header().Get("Access-Control-Allow-Origin"), "https://app.example.com")
+	assert.NotEqual(t, rr.Header().Get("Access-Control-Expose-Headers"), "")
+
+	req = httptest.NewRequest("GET", "/posts", nil)
+	rr = httptest.NewRecorder()
+	corsHandler().ServeHTTP(rr, req)
+	assert.Equal(t, rr.Header().Get("Access-Control-Allow-Origin"), "")
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stylll/GoBlog/api/migrations"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestHealthz(t *testing.T) {
+	rr := httptest.NewRecorder()
+	server.Healthz(rr, httptest.NewRequest("GET", "/healthz", nil))
+	assert.Equal(t, rr.Code, http.StatusOK)
+}
+
+type readinessReport struct {
+	Status       string `json:"status"`
+	Dependencies map[string]struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	} `json:"dependencies"`
+}
+
+func probeReadyz(t *testing.T) (int, readinessReport) {
+	rr := httptest.NewRecorder()
+	server.Readyz(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+	report := readinessReport{}
+	err := json.Unmarshal(rr.Body.Bytes(), &report)
+	if err != nil {
+		t.Fatalf("cannot unmarshal readiness report: %v", err)
+	}
+	assert.Equal(t, report.Dependencies["database"].Status, "ok")
+
+	return rr.Code, report
+}
+
+func schemaMigrationsExists(t *testing.T) bool {
+	var exists bool
+	err := server.DB.DB().QueryRow("SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return exists
+}
+
+// The test database is built with AutoMigrate, so the migrations are recorded by hand
+// to control what readiness sees.
+func TestReadyz(t *testing.T) {
+	db := server.DB.DB()
+	_, err := db.Exec("DROP TABLE IF EXISTS schema_migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Exec("DROP TABLE IF EXISTS schema_migrations")
+
+	all := migrations.All()
+
+	// never migrated: everything is pending and the probe creates nothing
+	code, report := probeReadyz(t)
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+	assert.Equal(t, report.Status, "unavailable")
+	assert.Equal(t, report.Dependencies["migrations"].Status, "failing")
+	assert.Equal(t, strings.HasPrefix(report.Dependencies["migrations"].Error, fmt.Sprintf("%d pending migrations", len(all))), true)
+	assert.Equal(t, schemaMigrationsExists(t), false)
+
+	_, err = db.Exec("CREATE TABLE schema_migrations (version bigint PRIMARY KEY, name varchar(255) NOT NULL, applied_at timestamp with time zone NOT NULL DEFAULT CURRENT_TIMESTAMP)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range all {
+		_, err = db.Exec("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.Version, m.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	code, report = probeReadyz(t)
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, report.Status, "ok")
+	assert.Equal(t, report.Dependencies["migrations"].Status, "ok")
+
+	latest := all[len(all)-1]
+	_, err = db.Exec("DELETE FROM schema_migrations WHERE version = $1", latest.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, report = probeReadyz(t)
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+	assert.Equal(t, report.Dependencies["migrations"].Error, fmt.Sprintf("1 pending migrations, first is %04d_%s", latest.Version, latest.Name))
+}
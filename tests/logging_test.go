@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stylll/GoBlog/api/config"
+	"github.com/stylll/GoBlog/api/logger"
+	"github.com/stylll/GoBlog/api/middlewares"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestRequestLogging(t *testing.T) {
+	out := &bytes.Buffer{}
+	log, err := logger.New(out, config.Defaults().Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/posts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).Info("handled")
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := middlewares.SetMiddlewareRequestLogging(log, router, router)
+
+	req := httptest.NewRequest("GET", "/posts/42", nil)
+	req.Header.Set("X-Request-ID", "abc123")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Header().Get("X-Request-ID"), "abc123")
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	assert.Equal(t, len(lines), 2)
+	for _, line := range lines {
+		record := map[string]interface{}{}
+		err = json.Unmarshal(line, &record)
+		if err != nil {
+			t.Fatalf("cannot parse log line %s: %v", line, err)
+		}
+		assert.Equal(t, record["request_id"], "abc123")
+	}
+
+	access := map[string]interface{}{}
+	json.Unmarshal(lines[1], &access)
+	assert.Equal(t, access["route"], "/posts/{id}")
+	assert.Equal(t, access["method"], "GET")
+	assert.Equal(t, access["status"], float64(http.StatusTeapot))
+
+	// a generated ID is returned when the client does not send one
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/missing", nil))
+	assert.Equal(t, len(rr.Header().Get("X-Request-ID")), 32)
+}
@@ -3,12 +3,12 @@ package tests
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/stylll/GoBlog/api/apperror"
 	"github.com/stylll/GoBlog/api/models"
 	"gopkg.in/go-playground/assert.v1"
 )
@@ -32,33 +32,37 @@ func TestSignIn(t *testing.T) {
 	}
 
 	testCases := []struct {
-		email        string
-		password     string
-		errorMessage string
+		email     string
+		password  string
+		errorCode string
 	}{
 		{
-			email:        user.Email,
-			password:     "NardDog!",
-			errorMessage: "",
+			email:     user.Email,
+			password:  "NardDog!",
+			errorCode: "",
 		},
 		{
-			email:        user.Email,
-			password:     "Bernard",
-			errorMessage: "crypto/bcrypt: hashedPassword is not the hash of the given password",
+			email:     user.Email,
+			password:  "Bernard",
+			errorCode: "invalid_credentials",
 		},
 		{
-			email:        "wrong@email.com",
-			password:     "Bernard",
-			errorMessage: "record not found",
+			email:     "wrong@email.com",
+			password:  "Bernard",
+			errorCode: "invalid_credentials",
 		},
 	}
 
 	for _, i := range testCases {
-		token, err := server.SignIn(i.email, i.password)
-		if err != nil {
-			assert.Equal(t, errors.New(i.errorMessage), err)
+		token, err := server.SignIn(server.DB, i.email, i.password)
+		if i.errorCode != "" {
+			appErr, ok := err.(*apperror.Error)
+			assert.Equal(t, ok, true)
+			assert.Equal(t, appErr.Code, i.errorCode)
+			assert.Equal(t, appErr.Kind, apperror.KindUnauthorized)
 		} else {
-			assert.NotEqual(t, token, "")
+			assert.Equal(t, err, nil)
+			assert.NotEqual(t, token, nil)
 		}
 	}
 }
@@ -82,41 +86,47 @@ func TestLogin(t *testing.T) {
 	}
 
 	testCases := []struct {
-		inputJSON    string
-		statusCode   int
-		email        string
-		password     string
-		errorMessage string
+		inputJSON  string
+		statusCode int
+		errorCode  string
+		errorField string
 	}{
 		{
-			inputJSON:    `{"email": "pam.beesly@dundermifflin.com", "password": "Pamela20"}`,
-			statusCode:   200,
-			errorMessage: "",
+			inputJSON:  `{"email": "pam.beesly@dundermifflin.com", "password": "Pamela20"}`,
+			statusCode: 200,
+		},
+		{
+			inputJSON:  `{"email": "pam.beesly@dundermifflin.com", "password": "wrong password"}`,
+			statusCode: 401,
+			errorCode:  "invalid_credentials",
 		},
 		{
-			inputJSON:    `{"email": "pam.beesly@dundermifflin.com", "password": "wrong password"}`,
-			statusCode:   422,
-			errorMessage: "Incorrect Password",
+			inputJSON:  `{"email": "pam@dundermifflin.com", "password": "Pamela20"}`,
+			statusCode: 401,
+			errorCode:  "invalid_credentials",
 		},
 		{
-			inputJSON:    `{"email": "pam@dundermifflin.com", "password": "Pamela20"}`,
-			statusCode:   422,
-			errorMessage: "Incorrect details",
+			inputJSON:  `{"email": "dundermifflin.com", "password": "Pamela20"}`,
+			statusCode: 422,
+			errorCode:  "validation_failed",
+			errorField: "email",
 		},
 		{
-			inputJSON:    `{"email": "dundermifflin.com", "password": "Pamela20"}`,
-			statusCode:   400,
-			errorMessage: "Invalid Email",
+			inputJSON:  `{"email": "", "password": "Pamela20"}`,
+			statusCode: 422,
+			errorCode:  "validation_failed",
+			errorField: "email",
 		},
 		{
-			inputJSON:    `{"email": "", "password": "Pamela20"}`,
-			statusCode:   400,
-			errorMessage: "Required Email",
+			inputJSON:  `{"email": "pam.best@dundermifflin.com", "password": ""}`,
+			statusCode: 422,
+			errorCode:  "validation_failed",
+			errorField: "password",
 		},
 		{
-			inputJSON:    `{"email": "pam.best@dundermifflin.com", "password": ""}`,
-			statusCode:   400,
-			errorMessage: "Required Password",
+			inputJSON:  `{"email": `,
+			statusCode: 400,
+			errorCode:  "invalid_body",
 		},
 	}
 
@@ -135,14 +145,27 @@ func TestLogin(t *testing.T) {
 			assert.NotEqual(t, rr.Body.String(), "")
 		}
 
-		if i.statusCode == 422 && i.errorMessage != "" {
-			responseMap := make(map[string]interface{})
-			err := json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if i.errorCode != "" {
+			assert.Equal(t, rr.Header().Get("Content-Type"), "application/problem+json")
+
+			problem := struct {
+				Status int    `json:"status"`
+				Code   string `json:"code"`
+				Errors []struct {
+					Field string `json:"field"`
+				} `json:"errors"`
+			}{}
+			err := json.Unmarshal([]byte(rr.Body.String()), &problem)
 			if err != nil {
 				t.Errorf("Cannot convert response to json: %v", err)
 			}
 
-			assert.Equal(t, responseMap["error"], i.errorMessage)
+			assert.Equal(t, problem.Status, i.statusCode)
+			assert.Equal(t, problem.Code, i.errorCode)
+			if i.errorField != "" {
+				assert.Equal(t, len(problem.Errors), 1)
+				assert.Equal(t, problem.Errors[0].Field, i.errorField)
+			}
 		}
 	}
 }
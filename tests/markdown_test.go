@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stylll/GoBlog/api/dto"
+	"github.com/stylll/GoBlog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestPostMarkdown(t *testing.T) {
+	author, _, _, _, tokens := setupAuthz(t)
+
+	post := models.Post{
+		Title: "Markdown",
+		Content: "# Tips & tricks\n\n*Bold* <b onclick=\"x()\">move</b><script>alert(1)</script>\n\n" +
+			strings.Repeat("All work and no play makes Jack a dull boy. ", 50) + "\n\n```go\nfunc main() {}\n```",
+		AuthorID: author.ID,
+		Status:   models.PostStatusPublished,
+	}
+	_, err := post.SavePost(server.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := dto.PostResponse{}
+	serveJSON(t, "GET", "/posts/by-slug/markdown", "", "", http.StatusOK, &response)
+
+	// the source comes back as written, the HTML rendered and sanitized
+	assert.Equal(t, response.Content, post.Content)
+	assert.Equal(t, strings.Contains(response.ContentHTML, `<h1 id="tips--tricks">Tips &amp; tricks</h1>`), true)
+	assert.Equal(t, strings.Contains(response.ContentHTML, "<p><em>Bold</em> <b>move</b></p>"), true)
+	assert.Equal(t, strings.Contains(response.ContentHTML, `<span class="kd">func</span>`), true)
+	assert.Equal(t, strings.Contains(response.ContentHTML, "script"), false)
+	assert.Equal(t, strings.Contains(response.ContentHTML, "onclick"), false)
+
+	path := fmt.Sprintf("/posts/%d", post.ID)
+	update := `{"title": "Markdown", "content": "A [link](https://example.com)"}`
+	serveJSON(t, "PUT", path, tokens["jim"], update, http.StatusOK, &response)
+	assert.Equal(t, response.ContentHTML, "<p>A <a href=\"https://example.com\" rel=\"nofollow\">link</a></p>\n")
+
+	// HTML cached by an older renderer is rendered again when the post is read
+	err = server.DB.Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumns(
+		map[string]interface{}{"content_html": "", "content_html_version": 0},
+	).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveJSON(t, "GET", path, "", "", http.StatusOK, &response)
+	assert.Equal(t, strings.HasPrefix(response.ContentHTML, "<p>A <a"), true)
+}
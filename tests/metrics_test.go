@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stylll/GoBlog/api/metrics"
+	"github.com/stylll/GoBlog/api/middlewares"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/widgets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}).Methods("GET")
+	router.HandleFunc("/metrics", metrics.Default.Handler()).Methods("GET")
+	handler := middlewares.SetMiddlewareMetrics(router, router)
+
+	for _, path := range []string{"/widgets/1", "/widgets/2"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("BREW", "/widgets/3", nil))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	body := rr.Body.String()
+	assert.Equal(t, strings.Contains(body, `goblog_http_requests_total{method="GET",route="/widgets/{id}",status="202"} 2`), true)
+	assert.Equal(t, strings.Contains(body, `goblog_http_request_duration_seconds_count{method="GET",route="/widgets/{id}",status="202"} 2`), true)
+	assert.Equal(t, strings.Contains(body, "# TYPE goblog_db_open_connections gauge"), true)
+	// unknown methods share one label value
+	assert.Equal(t, strings.Contains(body, `method="BREW"`), false)
+	assert.Equal(t, strings.Contains(body, `goblog_http_requests_total{method="other",route="unmatched",status="405"} 1`), true)
+}
+
+func TestMetricsNotPublic(t *testing.T) {
+	server.InitializeRoutes()
+
+	rr := httptest.NewRecorder()
+	server.Router.ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, rr.Code, http.StatusNotFound)
+}
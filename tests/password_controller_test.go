@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stylll/GoBlog/api/mailer"
+	"github.com/stylll/GoBlog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+var tokenLinkPattern = regexp.MustCompile(`token=(\S+)`)
+
+func TestPasswordReset(t *testing.T) {
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = refreshSessionTables()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = refreshUserTokenTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	user := models.User{
+		Firstname: "Jim",
+		Lastname:  "Halpert",
+		Email:     "jim.halpert@dundermifflin.com",
+		Password:  "BigTuna1",
+	}
+
+	err = seedSingleUser(&user)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	forgotCases := []struct {
+		inputJSON  string
+		statusCode int
+	}{
+		{inputJSON: `{"email": "jim.halpert@dundermifflin.com"}`, statusCode: 202},
+		{inputJSON: `{"email": "nobody@dundermifflin.com"}`, statusCode: 202},
+		{inputJSON: `{"email": "dundermifflin.com"}`, statusCode: 422},
+		{inputJSON: `{"email": ""}`, statusCode: 422},
+	}
+
+	for _, i := range forgotCases {
+		req, err := http.NewRequest("POST", "/password/forgot", bytes.NewBufferString(i.inputJSON))
+		if err != nil {
+			t.Errorf("error occured: %v", err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.ForgotPassword)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, i.statusCode)
+	}
+	// the emails are sent in the background
+	server.Wait()
+
+	_, found := outbox.Last("nobody@dundermifflin.com")
+	assert.Equal(t, found, false)
+
+	message, found := outbox.Last(user.Email)
+	assert.Equal(t, found, true)
+
+	// the link opens the frontend's reset page, which posts to the API
+	match := tokenLinkPattern.FindStringSubmatch(message.Body)
+	if len(match) != 2 {
+		t.Fatalf("no reset link in email: %q", message.Body)
+	}
+	assert.Equal(t, strings.Contains(message.Body, server.Config.PasswordResetURL+"?token="), true)
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resetCases := []struct {
+		inputJSON  string
+		statusCode int
+	}{
+		{inputJSON: fmt.Sprintf(`{"token": "%s", "password": ""}`, token), statusCode: 422},
+		{inputJSON: `{"token": "made-up", "password": "Tuna2020"}`, statusCode: 400},
+		{inputJSON: fmt.Sprintf(`{"token": "%s", "password": "Tuna2020"}`, token), statusCode: 200},
+		// tokens are single use
+		{inputJSON: fmt.Sprintf(`{"token": "%s", "password": "Tuna2021"}`, token), statusCode: 400},
+	}
+
+	for _, i := range resetCases {
+		req, err := http.NewRequest("POST", "/password/reset", bytes.NewBufferString(i.inputJSON))
+		if err != nil {
+			t.Errorf("error occured: %v", err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.ResetPassword)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, i.statusCode)
+	}
+
+	_, err = server.SignIn(server.DB, user.Email, "BigTuna1")
+	assert.NotEqual(t, err, nil)
+
+	_, err = server.SignIn(server.DB, user.Email, "Tuna2020")
+	assert.Equal(t, err, nil)
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(msg mailer.Message) error {
+	return errors.New("smtp: connection refused")
+}
+
+func TestForgotPasswordMailFailure(t *testing.T) {
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = refreshUserTokenTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	user := models.User{
+		Firstname: "Pam",
+		Lastname:  "Beesly",
+		Email:     "pam.beesly@dundermifflin.com",
+		Password:  "Sketch2020",
+	}
+
+	err = seedSingleUser(&user)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server.Mailer = failingMailer{}
+	defer func() { server.Mailer = outbox }()
+
+	// a mail failure is logged, not reported, so it cannot reveal the account
+	for _, email := range []string{user.Email, "nobody@dundermifflin.com"} {
+		req := httptest.NewRequest("POST", "/password/forgot", bytes.NewBufferString(fmt.Sprintf(`{"email": "%s"}`, email)))
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.ForgotPassword).ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, http.StatusAccepted)
+	}
+	server.Wait()
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stylll/GoBlog/api/models"
+	"github.com/stylll/GoBlog/api/utils/pagination"
+)
+
+var queryCount int64
+var countQueriesOnce sync.Once
+
+// countQueries hooks gorm so every SELECT, including counts, bumps queryCount.
+func countQueries() {
+	countQueriesOnce.Do(func() {
+		count := func(scope *gorm.Scope) { atomic.AddInt64(&queryCount, 1) }
+		server.DB.Callback().Query().After("gorm:query").Register("tests:count_queries", count)
+		server.DB.Callback().RowQuery().After("gorm:row_query").Register("tests:count_queries", count)
+	})
+}
+
+func seedPostDataset(authors, postsPerAuthor int) error {
+	err := refreshUserTable()
+	if err != nil {
+		return err
+	}
+
+	err = refreshPostTable()
+	if err != nil {
+		return err
+	}
+
+	for a := 0; a < authors; a++ {
+		user := models.User{
+			Firstname: "Author",
+			Lastname:  fmt.Sprintf("%03d", a),
+			Email:     fmt.Sprintf("author%03d@dundermifflin.com", a),
+			Password:  "password",
+		}
+
+		err = seedSingleUser(&user)
+		if err != nil {
+			return err
+		}
+
+		for p := 0; p < postsPerAuthor; p++ {
+			post := models.Post{
+				Title:    fmt.Sprintf("Post %03d-%03d", a, p),
+				Content:  "Seeded for benchmarks",
+				AuthorID: user.ID,
+				Status:   models.PostStatusPublished,
+			}
+
+			err = seedSinglePost(&post)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func queriesForPage(limit int) (int64, error) {
+	page, err := pagination.Parse(url.Values{"limit": {fmt.Sprint(limit)}}, models.PostSortFields, "created_at")
+	if err != nil {
+		return 0, err
+	}
+
+	post := models.Post{}
+	before := atomic.LoadInt64(&queryCount)
+	posts, _, _, err := post.FindAllPosts(server.DB, models.PostFilter{}, page)
+	if err != nil {
+		return 0, err
+	}
+	if len(*posts) != limit {
+		return 0, fmt.Errorf("expected %d posts, got %d", limit, len(*posts))
+	}
+
+	return atomic.LoadInt64(&queryCount) - before, nil
+}
+
+func TestFindAllPostsQueryCount(t *testing.T) {
+	countQueries()
+
+	err := seedPostDataset(25, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	small, err := queriesForPage(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	large, err := queriesForPage(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if small != large {
+		t.Errorf("query count grew with page size: %d queries for 5 posts, %d for 100", small, large)
+	}
+}
+
+func BenchmarkFindAllPosts(b *testing.B) {
+	countQueries()
+
+	err := seedPostDataset(50, 4)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var baseline int64
+	for _, limit := range []int{10, 50, 100} {
+		b.Run(fmt.Sprintf("limit=%d", limit), func(b *testing.B) {
+			var total int64
+			for i := 0; i < b.N; i++ {
+				queries, err := queriesForPage(limit)
+				if err != nil {
+					b.Fatal(err)
+				}
+				total += queries
+			}
+
+			perOp := total / int64(b.N)
+			b.ReportMetric(float64(perOp), "queries/op")
+
+			if baseline == 0 {
+				baseline = perOp
+			}
+			if perOp != baseline {
+				b.Errorf("query count grew with page size: %d queries/op, expected %d", perOp, baseline)
+			}
+		})
+	}
+}
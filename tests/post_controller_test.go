@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stylll/GoBlog/api/dto"
+	"github.com/stylll/GoBlog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+type postPage struct {
+	Data  []dto.PostResponse `json:"data"`
+	Total int64              `json:"total"`
+	Links struct {
+		Next *string `json:"next"`
+		Prev *string `json:"prev"`
+	} `json:"links"`
+}
+
+func seedUserAndPosts(count int) (models.User, error) {
+	err := refreshUserTable()
+	if err != nil {
+		return models.User{}, err
+	}
+
+	err = refreshPostTable()
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{
+		Firstname: "Stanley",
+		Lastname:  "Hudson",
+		Email:     "stanley.hudson@dundermifflin.com",
+		Password:  "Pretzel1",
+	}
+
+	err = seedSingleUser(&user)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < count; i++ {
+		post := models.Post{
+			Title:     fmt.Sprintf("Crossword %02d", i),
+			Content:   "Did I stutter?",
+			AuthorID:  user.ID,
+			Status:    models.PostStatusPublished,
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+			UpdatedAt: start.Add(time.Duration(i) * time.Minute),
+		}
+
+		err = seedSinglePost(&post)
+		if err != nil {
+			return models.User{}, err
+		}
+	}
+
+	return user, nil
+}
+
+func getPostPage(t *testing.T, url string) postPage {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.GetAllPosts)
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	page := postPage{}
+	err = json.Unmarshal(rr.Body.Bytes(), &page)
+	if err != nil {
+		t.Errorf("Cannot convert response to json: %v", err)
+	}
+
+	return page
+}
+
+func TestGetAllPostsPagination(t *testing.T) {
+	_, err := seedUserAndPosts(5)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	first := getPostPage(t, "/posts?limit=2&sort=title&order=asc")
+	assert.Equal(t, first.Total, int64(5))
+	assert.Equal(t, len(first.Data), 2)
+	assert.Equal(t, first.Data[0].Title, "Crossword 00")
+	assert.Equal(t, first.Links.Prev, (*string)(nil))
+
+	second := getPostPage(t, *first.Links.Next)
+	assert.Equal(t, second.Data[0].Title, "Crossword 02")
+	assert.Equal(t, second.Data[1].Title, "Crossword 03")
+
+	third := getPostPage(t, *second.Links.Next)
+	assert.Equal(t, len(third.Data), 1)
+	assert.Equal(t, third.Data[0].Title, "Crossword 04")
+	assert.Equal(t, third.Links.Next, (*string)(nil))
+
+	back := getPostPage(t, *third.Links.Prev)
+	assert.Equal(t, back.Data[0].Title, "Crossword 02")
+	assert.Equal(t, back.Data[1].Title, "Crossword 03")
+
+	newest := getPostPage(t, "/posts?limit=1")
+	assert.Equal(t, newest.Data[0].Title, "Crossword 04")
+}
+
+func TestGetAllPostsInvalidParams(t *testing.T) {
+	_, err := seedUserAndPosts(1)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, url := range []string{
+		"/posts?sort=content",
+		"/posts?order=sideways",
+		"/posts?limit=0",
+		"/posts?cursor=garbage",
+		"/posts?author=me",
+		"/posts?from=yesterday",
+	} {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Errorf("error occured: %v", err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.GetAllPosts)
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"encoding/json"
+	"log"
+	"testing"
+
+	"github.com/stylll/GoBlog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestPostExportImport(t *testing.T) {
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = refreshPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	user := models.User{Firstname: "Kevin", Lastname: "Malone", Email: "kevin@dundermifflin.com", Password: "Chili2020"}
+	err = seedSingleUser(&user)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	recipes := models.Category{Name: "Recipes"}
+	recipes.Prepare()
+	_, err = recipes.SaveCategory(server.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stews := models.Category{Name: "Stews & Soups"}
+	stews.Prepare()
+	stews.ParentID = &recipes.ID
+	_, err = stews.SaveCategory(server.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := models.Post{Title: "Famous chili", Content: "Undercook the onions", AuthorID: user.ID,
+		Status: models.PostStatusPublished, CategoryID: &stews.ID, Tags: []models.Tag{{Name: "Chili"}, {Name: "Q&A"}}}
+	for i := range post.Tags {
+		post.Tags[i].Prepare()
+	}
+	_, err = post.SavePost(server.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exported, err := models.ExportPosts(server.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(exported), 1)
+	assert.Equal(t, exported[0].Tags, []string{"Chili", "Q&A"})
+	assert.Equal(t, exported[0].Category, []string{"Recipes", "Stews & Soups"})
+
+	// import into an empty database through the JSON the CLI writes
+	body, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := []models.ExportedPost{}
+	err = json.Unmarshal(body, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = refreshPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// the top-level category exists already and is reused
+	existing := models.Category{Name: "Recipes"}
+	existing.Prepare()
+	_, err = existing.SaveCategory(server.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created, skipped, err := models.ImportPosts(server.DB, decoded)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, created, 1)
+	assert.Equal(t, skipped, 0)
+
+	created, skipped, err = models.ImportPosts(server.DB, decoded)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, created, 0)
+	assert.Equal(t, skipped, 1)
+
+	reexported, err := models.ExportPosts(server.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(reexported), 1)
+	assert.Equal(t, reexported[0].Tags, exported[0].Tags)
+	assert.Equal(t, reexported[0].Category, exported[0].Category)
+
+	var categories int
+	err = server.DB.Model(&models.Category{}).Count(&categories).Error
+	assert.Equal(t, err, nil)
+	assert.Equal(t, categories, 2)
+}
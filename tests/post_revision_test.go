@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stylll/GoBlog/api/dto"
+	"github.com/stylll/GoBlog/api/models"
+	"github.com/stylll/GoBlog/api/utils/diff"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestPostRevisions(t *testing.T) {
+	author, _, editor, _, tokens := setupAuthz(t)
+
+	post := models.Post{Title: "Memo", Content: "Line one\nLine two", AuthorID: author.ID}
+	err := seedSinglePost(&post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/posts/%d", post.ID)
+
+	runAuthzCases(t, []authzCase{
+		{"edit owner", "PUT", path, tokens["jim"], `{"title": "Memo", "content": "Line one\nLine 2"}`, http.StatusOK},
+		{"edit editor", "PUT", path, tokens["angela"], `{"title": "Memo v3", "content": "Line one\nLine 2\nLine three"}`, http.StatusOK},
+
+		{"history anonymous", "GET", path + "/revisions", "", "", http.StatusUnauthorized},
+		{"history other author", "GET", path + "/revisions", tokens["dwight"], "", http.StatusForbidden},
+		{"history missing post", "GET", "/posts/999999/revisions", tokens["jim"], "", http.StatusNotFound},
+		{"diff bad revision", "GET", path + "/revisions/diff?from=abc&to=2", tokens["jim"], "", http.StatusBadRequest},
+		{"diff missing revision", "GET", path + "/revisions/diff?from=1&to=9", tokens["jim"], "", http.StatusNotFound},
+		{"restore other author", "POST", path + "/revisions/1/restore", tokens["dwight"], "", http.StatusForbidden},
+		{"restore missing revision", "POST", path + "/revisions/9/restore", tokens["jim"], "", http.StatusNotFound},
+	})
+
+	revisions := []dto.PostRevisionResponse{}
+	serveJSON(t, "GET", path+"/revisions", tokens["jim"], "", http.StatusOK, &revisions)
+	assert.Equal(t, len(revisions), 3)
+	assert.Equal(t, revisions[0].Number, 3)
+	assert.Equal(t, *revisions[0].EditorID, editor.ID)
+	assert.Equal(t, revisions[2].Content, "Line one\nLine two")
+
+	changes := dto.RevisionDiffResponse{}
+	serveJSON(t, "GET", path+"/revisions/diff?from=1&to=3", tokens["jim"], "", http.StatusOK, &changes)
+	assert.Equal(t, changes.Content, []diff.Line{
+		{Op: diff.Equal, Text: "Line one"},
+		{Op: diff.Delete, Text: "Line two"},
+		{Op: diff.Insert, Text: "Line 2"},
+		{Op: diff.Insert, Text: "Line three"},
+	})
+
+	runAuthzCases(t, []authzCase{
+		{"restore owner", "POST", path + "/revisions/1/restore", tokens["jim"], "", http.StatusOK},
+	})
+
+	restored := models.Post{}
+	_, err = restored.FindPostByID(server.DB, post.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, restored.Title, "Memo")
+	assert.Equal(t, restored.Content, "Line one\nLine two")
+
+	// restoring adds to the history instead of rewriting it
+	serveJSON(t, "GET", path+"/revisions", tokens["jim"], "", http.StatusOK, &revisions)
+	assert.Equal(t, len(revisions), 4)
+	assert.Equal(t, *revisions[0].RestoredFrom, 1)
+	assert.Equal(t, *revisions[0].EditorID, author.ID)
+}
+
+func TestPostRevisionDiffLimit(t *testing.T) {
+	author, _, _, _, tokens := setupAuthz(t)
+
+	// maximum-size revisions of one short line each
+	lines := func(text string) string {
+		return strings.Repeat(text+"\n", models.PostContentMaxLength/2-1) + text
+	}
+	post := models.Post{Title: "Lines", Content: lines("a"), AuthorID: author.ID}
+	err := seedSinglePost(&post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/posts/%d", post.ID)
+
+	edit := func(content string) string {
+		body, err := json.Marshal(map[string]string{"title": "Lines", "content": content})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+	runAuthzCases(t, []authzCase{
+		{"edit one line", "PUT", path, tokens["jim"], edit("b\n" + lines("a")[2:]), http.StatusOK},
+		{"rewrite every line", "PUT", path, tokens["jim"], edit(lines("c")), http.StatusOK},
+	})
+
+	// a small edit of a long post is compared around the shared lines
+	changes := dto.RevisionDiffResponse{}
+	serveJSON(t, "GET", path+"/revisions/diff?from=1&to=2", tokens["jim"], "", http.StatusOK, &changes)
+	assert.Equal(t, len(changes.Content), models.PostContentMaxLength/2+1)
+	assert.Equal(t, changes.Content[0], diff.Line{Op: diff.Delete, Text: "a"})
+	assert.Equal(t, changes.Content[1], diff.Line{Op: diff.Insert, Text: "b"})
+
+	// two entirely different long revisions are refused instead of compared
+	problem := struct {
+		Code string `json:"code"`
+	}{}
+	serveJSON(t, "GET", path+"/revisions/diff?from=2&to=3", tokens["jim"], "", http.StatusUnprocessableEntity, &problem)
+	assert.Equal(t, problem.Code, "diff_too_large")
+}
@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stylll/GoBlog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestPostSlugs(t *testing.T) {
+	author, _, _, _, tokens := setupAuthz(t)
+
+	seed := func(title, status string) models.Post {
+		post := models.Post{Title: title, Content: "Burn the sugar", AuthorID: author.ID, Status: status}
+		err := seedSinglePost(&post)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return post
+	}
+
+	first := seed("Crème Brûlée", models.PostStatusPublished)
+	second := seed("Crème brûlée!", models.PostStatusPublished)
+	draft := seed("Привет, мир", models.PostStatusDraft)
+	assert.Equal(t, first.Slug, "creme-brulee")
+	assert.Equal(t, second.Slug, "creme-brulee-2")
+	assert.Equal(t, draft.Slug, "privet-mir")
+
+	runAuthzCases(t, []authzCase{
+		{"by slug", "GET", "/posts/by-slug/creme-brulee", "", "", http.StatusOK},
+		{"unknown slug", "GET", "/posts/by-slug/tiramisu", "", "", http.StatusNotFound},
+		{"draft anonymous", "GET", "/posts/by-slug/privet-mir", "", "", http.StatusNotFound},
+		{"draft owner", "GET", "/posts/by-slug/privet-mir", tokens["jim"], "", http.StatusOK},
+		{"rename", "PUT", fmt.Sprintf("/posts/%d", first.ID), tokens["jim"], `{"title": "Crème Brûlée for Beginners", "content": "Burn the sugar"}`, http.StatusOK},
+	})
+
+	req := httptest.NewRequest("GET", "/posts/by-slug/creme-brulee", nil)
+	rr := httptest.NewRecorder()
+	server.Router.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusMovedPermanently)
+	assert.Equal(t, rr.Header().Get("Location"), "/posts/by-slug/creme-brulee-for-beginners")
+
+	// the old slug stays with the renamed post
+	third := seed("Crème Brûlée", models.PostStatusPublished)
+	assert.Equal(t, third.Slug, "creme-brulee-3")
+}
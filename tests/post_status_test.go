@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stylll/GoBlog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestPostPublication(t *testing.T) {
+	author, _, _, _, tokens := setupAuthz(t)
+
+	draft := models.Post{Title: "Draft memo", Content: "Not yet", AuthorID: author.ID}
+	err := seedSinglePost(&draft)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/posts/%d", draft.ID)
+
+	runAuthzCases(t, []authzCase{
+		{"read draft anonymous", "GET", path, "", "", http.StatusNotFound},
+		{"read draft other author", "GET", path, tokens["dwight"], "", http.StatusNotFound},
+		{"read draft owner", "GET", path, tokens["jim"], "", http.StatusOK},
+		{"read draft editor", "GET", path, tokens["angela"], "", http.StatusOK},
+
+		{"publish anonymous", "POST", path + "/publish", "", "", http.StatusUnauthorized},
+		{"publish other author", "POST", path + "/publish", tokens["dwight"], "", http.StatusForbidden},
+		{"publish owner", "POST", path + "/publish", tokens["jim"], "", http.StatusOK},
+		{"publish again", "POST", path + "/publish", tokens["jim"], "", http.StatusConflict},
+		{"read published anonymous", "GET", path, "", "", http.StatusOK},
+
+		{"archive", "POST", path + "/unpublish", tokens["jim"], `{"archive": true}`, http.StatusOK},
+		{"archive again", "POST", path + "/unpublish", tokens["jim"], `{"archive": true}`, http.StatusConflict},
+		{"read archived anonymous", "GET", path, "", "", http.StatusNotFound},
+	})
+
+	post := models.Post{}
+	_, err = post.FindPostByID(server.DB, draft.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, post.Status, models.PostStatusArchived)
+	assert.NotEqual(t, post.PublishedAt, (*time.Time)(nil))
+}
+
+func TestPublishDuePosts(t *testing.T) {
+	author, _, _, _, _ := setupAuthz(t)
+
+	at := time.Now().Add(time.Hour)
+	scheduled := models.Post{Title: "Scheduled memo", Content: "Soon", AuthorID: author.ID, Status: models.PostStatusScheduled, PublishedAt: &at}
+	err := seedSinglePost(&scheduled)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := models.PublishDuePosts(server.DB, time.Now())
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, int64(0))
+
+	count, err = models.PublishDuePosts(server.DB, at.Add(time.Minute))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, count, int64(1))
+
+	post := models.Post{}
+	_, err = post.FindPostByID(server.DB, scheduled.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, post.Status, models.PostStatusPublished)
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stylll/GoBlog/api/middlewares"
+	"github.com/stylll/GoBlog/api/models"
+	"github.com/stylll/GoBlog/api/ratelimit"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter := &ratelimit.Limiter{
+		Name:  "test",
+		Store: ratelimit.NewMemoryStore(),
+		Limit: ratelimit.Limit{Requests: 2, Window: time.Minute},
+		Key:   ratelimit.ByIP(false),
+	}
+	handler := middlewares.SetMiddlewareRateLimit(limiter, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/login", nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	rr := request("10.0.0.1:5000")
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, rr.Header().Get("X-RateLimit-Limit"), "2")
+	assert.Equal(t, rr.Header().Get("X-RateLimit-Remaining"), "1")
+
+	rr = request("10.0.0.1:5001")
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, rr.Header().Get("X-RateLimit-Remaining"), "0")
+
+	rr = request("10.0.0.1:5002")
+	assert.Equal(t, rr.Code, http.StatusTooManyRequests)
+	assert.Equal(t, rr.Header().Get("Retry-After"), "30")
+
+	// other clients have their own bucket
+	rr = request("10.0.0.2:5000")
+	assert.Equal(t, rr.Code, http.StatusOK)
+}
+
+func TestRefreshRateLimit(t *testing.T) {
+	err := refreshSessionTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login := server.Config.RateLimit.Login
+	server.RateLimits = ratelimit.NewMemoryStore()
+	server.Config.RateLimit.Login = "2/1m"
+	server.InitializeRoutes()
+	defer func() {
+		server.RateLimits = nil
+		server.Config.RateLimit.Login = login
+		server.InitializeRoutes()
+	}()
+
+	// guessing refresh tokens is throttled like guessing passwords
+	runAuthzCases(t, []authzCase{
+		{"first guess", "POST", "/token/refresh", "", `{"refresh_token": "guess-1"}`, http.StatusUnauthorized},
+		{"second guess", "POST", "/token/refresh", "", `{"refresh_token": "guess-2"}`, http.StatusUnauthorized},
+		{"third guess", "POST", "/token/refresh", "", `{"refresh_token": "guess-3"}`, http.StatusTooManyRequests},
+	})
+}
+
+func TestLoginLockout(t *testing.T) {
+	err := refreshUserTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = refreshSessionTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := models.User{Firstname: "Stanley", Lastname: "Hudson", Email: "stanley@dundermifflin.com", Password: "Pretzels1"}
+	err = seedSingleUser(&user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := ratelimit.NewMemoryStore()
+	server.Lockout = ratelimit.NewLockout(store, 3, time.Minute, time.Hour)
+	defer func() { server.Lockout = nil }()
+
+	var wrongPassword error
+	for i := 0; i < 2; i++ {
+		_, wrongPassword = server.SignIn(server.DB, user.Email, "wrong")
+		_, locked := wrongPassword.(*ratelimit.LockedError)
+		assert.Equal(t, locked, false)
+	}
+
+	_, err = server.SignIn(server.DB, user.Email, "wrong")
+	locked, ok := err.(*ratelimit.LockedError)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, locked.RetryAfter, time.Minute)
+
+	// the right password does not help while the account is locked
+	_, err = server.SignIn(server.DB, user.Email, "Pretzels1")
+	_, ok = err.(*ratelimit.LockedError)
+	assert.Equal(t, ok, true)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/login", bytes.NewBufferString(`{"email": "stanley@dundermifflin.com", "password": "Pretzels1"}`))
+	server.Login(rr, req)
+	assert.Equal(t, rr.Code, http.StatusTooManyRequests)
+	assert.NotEqual(t, rr.Header().Get("Retry-After"), "")
+	assert.Equal(t, rr.Header().Get("Content-Type"), "application/problem+json")
+	assert.Equal(t, strings.Contains(rr.Body.String(), `"code":"account_locked"`), true)
+
+	// a replica sharing the store sees the lock
+	replica := ratelimit.NewLockout(store, 3, time.Minute, time.Hour)
+	_, ok = replica.Check(user.Email).(*ratelimit.LockedError)
+	assert.Equal(t, ok, true)
+
+	// unknown emails fail and lock exactly like existing ones
+	for i := 0; i < 2; i++ {
+		_, err = server.SignIn(server.DB, "nobody@dundermifflin.com", "wrong")
+		assert.Equal(t, err, wrongPassword)
+	}
+	_, err = server.SignIn(server.DB, "nobody@dundermifflin.com", "wrong")
+	locked, ok = err.(*ratelimit.LockedError)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, locked.RetryAfter, time.Minute)
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stylll/GoBlog/api/dto"
+	"github.com/stylll/GoBlog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestTagsAndCategories(t *testing.T) {
+	author, _, _, _, tokens := setupAuthz(t)
+
+	recipes, desserts := dto.CategoryResponse{}, dto.CategoryResponse{}
+	serveJSON(t, "POST", "/categories", tokens["angela"], `{"name": "Recipes"}`, http.StatusCreated, &recipes)
+	serveJSON(t, "POST", "/categories", tokens["angela"], fmt.Sprintf(`{"name": "Desserts", "parent_id": %d}`, recipes.ID), http.StatusCreated, &desserts)
+	assert.Equal(t, desserts.Slug, "desserts")
+	assert.Equal(t, *desserts.ParentID, recipes.ID)
+
+	seed := func(title string, categoryID *int, tags ...string) models.Post {
+		post := models.Post{Title: title, Content: "Mix well", AuthorID: author.ID, Status: models.PostStatusPublished, CategoryID: categoryID}
+		for _, name := range tags {
+			post.Tags = append(post.Tags, models.Tag{Name: name})
+		}
+		_, err := post.SavePost(server.DB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return post
+	}
+
+	seed("Crème brûlée", &desserts.ID, "French", "Sugar")
+	seed("Pot-au-feu", &recipes.ID, "french")
+	stew := seed("Irish stew", nil)
+
+	runAuthzCases(t, []authzCase{
+		{"tag author", "POST", "/tags", tokens["jim"], `{"name": "Spicy"}`, http.StatusForbidden},
+		{"tag editor", "POST", "/tags", tokens["angela"], `{"name": "Spicy"}`, http.StatusCreated},
+		{"tag duplicate", "POST", "/tags", tokens["angela"], `{"name": "SPICY"}`, http.StatusConflict},
+		{"category author", "POST", "/categories", tokens["jim"], `{"name": "Drinks"}`, http.StatusForbidden},
+		{"category missing parent", "POST", "/categories", tokens["angela"], `{"name": "Drinks", "parent_id": 999999}`, http.StatusUnprocessableEntity},
+		{"category cycle", "PUT", fmt.Sprintf("/categories/%d", recipes.ID), tokens["angela"], fmt.Sprintf(`{"name": "Recipes", "parent_id": %d}`, desserts.ID), http.StatusUnprocessableEntity},
+		{"category with children", "DELETE", fmt.Sprintf("/categories/%d", recipes.ID), tokens["michael"], "", http.StatusConflict},
+		{"tag on update", "PUT", fmt.Sprintf("/posts/%d", stew.ID), tokens["jim"], fmt.Sprintf(`{"title": "Irish stew", "content": "Simmer", "category_id": %d, "tags": ["Spicy"]}`, recipes.ID), http.StatusOK},
+	})
+
+	cloud := []dto.TagCloudEntry{}
+	serveJSON(t, "GET", "/tags", "", "", http.StatusOK, &cloud)
+	assert.Equal(t, len(cloud), 3)
+	assert.Equal(t, cloud[0].Slug, "french")
+	assert.Equal(t, cloud[0].Count, 2)
+
+	page := postPage{}
+	serveJSON(t, "GET", "/posts?tag=french", "", "", http.StatusOK, &page)
+	assert.Equal(t, page.Total, int64(2))
+
+	// a category lists the posts of its subcategories too
+	serveJSON(t, "GET", "/posts?category=recipes", "", "", http.StatusOK, &page)
+	assert.Equal(t, page.Total, int64(3))
+	serveJSON(t, "GET", "/posts?category=desserts", "", "", http.StatusOK, &page)
+	assert.Equal(t, page.Total, int64(1))
+	assert.Equal(t, page.Data[0].Tags[0].Name, "French")
+	assert.Equal(t, page.Data[0].Category.ID, desserts.ID)
+
+	tree := []dto.CategoryNode{}
+	serveJSON(t, "GET", "/categories", "", "", http.StatusOK, &tree)
+	assert.Equal(t, len(tree), 1)
+	assert.Equal(t, tree[0].Children[0].Slug, "desserts")
+
+	// an edit without tags or category_id keeps them, explicit empty values clear them
+	path := fmt.Sprintf("/posts/%d", stew.ID)
+	post := dto.PostResponse{}
+	serveJSON(t, "PUT", path, tokens["jim"], `{"title": "Irish stew", "content": "Simmer longer"}`, http.StatusOK, &post)
+	assert.Equal(t, len(post.Tags), 1)
+	assert.Equal(t, post.Category.ID, recipes.ID)
+
+	serveJSON(t, "PUT", path, tokens["jim"], `{"title": "Irish stew", "content": "Simmer", "tags": []}`, http.StatusOK, &post)
+	assert.Equal(t, len(post.Tags), 0)
+	assert.Equal(t, post.Category.ID, recipes.ID)
+
+	post = dto.PostResponse{}
+	serveJSON(t, "PUT", path, tokens["jim"], `{"title": "Irish stew", "content": "Simmer", "category_id": null}`, http.StatusOK, &post)
+	assert.Equal(t, post.Category == nil, true)
+}
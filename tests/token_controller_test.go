@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stylll/GoBlog/api/auth"
+	"github.com/stylll/GoBlog/api/middlewares"
+	"github.com/stylll/GoBlog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func seedSignedInUser() (*auth.TokenDetails, error) {
+	err := refreshUserTable()
+	if err != nil {
+		return nil, err
+	}
+
+	err = refreshSessionTables()
+	if err != nil {
+		return nil, err
+	}
+
+	user := models.User{
+		Firstname: "Dwight",
+		Lastname:  "Schrute",
+		Email:     "dwight.schrute@dundermifflin.com",
+		Password:  "Beets123",
+	}
+
+	err = seedSingleUser(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	return server.SignIn(server.DB, user.Email, "Beets123")
+}
+
+func refreshRequest(refreshToken string) *httptest.ResponseRecorder {
+	inputJSON := fmt.Sprintf(`{"refresh_token": "%s"}`, refreshToken)
+	req, err := http.NewRequest("POST", "/token/refresh", bytes.NewBufferString(inputJSON))
+	if err != nil {
+		log.Fatalf("error occured: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.RefreshToken)
+	handler.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	tokens, err := seedSignedInUser()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rr := refreshRequest(tokens.RefreshToken)
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	rotated := auth.TokenDetails{}
+	err = json.Unmarshal(rr.Body.Bytes(), &rotated)
+	if err != nil {
+		t.Errorf("Cannot convert response to json: %v", err)
+	}
+	assert.NotEqual(t, rotated.RefreshToken, "")
+	assert.NotEqual(t, rotated.RefreshToken, tokens.RefreshToken)
+	assert.NotEqual(t, rotated.AccessToken, "")
+
+	// presenting the rotated-out token again revokes the whole session
+	rr = refreshRequest(tokens.RefreshToken)
+	assert.Equal(t, rr.Code, http.StatusUnauthorized)
+
+	rr = refreshRequest(rotated.RefreshToken)
+	assert.Equal(t, rr.Code, http.StatusUnauthorized)
+
+	rr = refreshRequest("not-a-token")
+	assert.Equal(t, rr.Code, http.StatusUnauthorized)
+}
+
+func TestLogout(t *testing.T) {
+	tokens, err := seedSignedInUser()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	protected := middlewares.SetMiddlewareAuthentication(server.DB, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req, err := http.NewRequest("GET", "/protected", nil)
+	if err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+
+	rr := httptest.NewRecorder()
+	protected.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	req, err = http.NewRequest("POST", "/logout", nil)
+	if err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+
+	rr = httptest.NewRecorder()
+	middlewares.SetMiddlewareAuthentication(server.DB, server.Logout).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusNoContent)
+
+	req, err = http.NewRequest("GET", "/protected", nil)
+	if err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+
+	rr = httptest.NewRecorder()
+	protected.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusUnauthorized)
+
+	rr = refreshRequest(tokens.RefreshToken)
+	assert.Equal(t, rr.Code, http.StatusUnauthorized)
+}
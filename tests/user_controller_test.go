@@ -0,0 +1,281 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stylll/GoBlog/api/middlewares"
+	"github.com/stylll/GoBlog/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestEmailVerification(t *testing.T) {
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = refreshUserTokenTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	inputJSON := `{"firstname": "Kelly", "lastname": "Kapoor", "email": "kelly.kapoor@dundermifflin.com", "password": "Ryan4ever", "verified_at": "2019-01-01T00:00:00Z"}`
+	req, err := http.NewRequest("POST", "/users", bytes.NewBufferString(inputJSON))
+	if err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.CreateUser)
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusCreated)
+
+	user := models.User{}
+	_, err = user.FindUserByEmail(server.DB, "kelly.kapoor@dundermifflin.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, user.IsVerified(), false)
+
+	message, found := outbox.Last(user.Email)
+	assert.Equal(t, found, true)
+
+	match := tokenLinkPattern.FindStringSubmatch(message.Body)
+	if len(match) != 2 {
+		t.Fatalf("no verification link in email: %q", message.Body)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		token      string
+		statusCode int
+	}{
+		{token: "", statusCode: 422},
+		{token: "made-up", statusCode: 400},
+		{token: token, statusCode: 200},
+		{token: token, statusCode: 400},
+	}
+
+	for _, i := range testCases {
+		req, err := http.NewRequest("GET", "/users/verify?token="+url.QueryEscape(i.token), nil)
+		if err != nil {
+			t.Errorf("error occured: %v", err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.VerifyEmail)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, i.statusCode)
+	}
+
+	verified := models.User{}
+	_, err = verified.FindUserByID(server.DB, uint64(user.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, verified.IsVerified(), true)
+}
+
+func TestCreateUserValidation(t *testing.T) {
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	inputJSON := `{"firstname": "", "lastname": "Flenderson", "email": "toby.dundermifflin.com", "password": "scranton"}`
+	req, err := http.NewRequest("POST", "/users", bytes.NewBufferString(inputJSON))
+	if err != nil {
+		t.Errorf("error occured: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(server.CreateUser)
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusUnprocessableEntity)
+
+	problem := struct {
+		Errors []struct {
+			Field string `json:"field"`
+			Code  string `json:"code"`
+		} `json:"errors"`
+	}{}
+	err = json.Unmarshal(rr.Body.Bytes(), &problem)
+	if err != nil {
+		t.Fatalf("Cannot convert response to json: %v", err)
+	}
+
+	// every invalid field is reported in one response
+	assert.Equal(t, len(problem.Errors), 3)
+	codes := map[string]string{}
+	for _, e := range problem.Errors {
+		codes[e.Field] = e.Code
+	}
+	assert.Equal(t, codes["firstname"], "required")
+	assert.Equal(t, codes["email"], "invalid_email")
+	assert.Equal(t, codes["password"], "weak_password")
+}
+
+func TestPasswordByteLimit(t *testing.T) {
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	testCases := []struct {
+		email      string
+		password   string
+		statusCode int
+	}{
+		// 72 characters but 142 bytes, beyond what bcrypt accepts
+		{email: "creed@dundermifflin.com", password: strings.Repeat("é", 70) + "a1", statusCode: http.StatusUnprocessableEntity},
+		{email: "creed.bratton@dundermifflin.com", password: strings.Repeat("é", 35) + "a1", statusCode: http.StatusCreated},
+	}
+
+	for _, i := range testCases {
+		inputJSON := fmt.Sprintf(`{"firstname": "Creed", "lastname": "Bratton", "email": %q, "password": %q}`, i.email, i.password)
+		req, err := http.NewRequest("POST", "/users", bytes.NewBufferString(inputJSON))
+		if err != nil {
+			t.Errorf("error occured: %v", err)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.CreateUser)
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, i.statusCode)
+	}
+}
+
+func TestGetUserViews(t *testing.T) {
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = refreshSessionTables()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	user := models.User{
+		Firstname: "Angela",
+		Lastname:  "Martin",
+		Email:     "angela.martin@dundermifflin.com",
+		Password:  "Sprinkles",
+	}
+
+	err = seedSingleUser(&user)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tokens, err := server.SignIn(server.DB, user.Email, "Sprinkles")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	testCases := []struct {
+		token     string
+		showEmail bool
+	}{
+		{token: "", showEmail: false},
+		{token: tokens.AccessToken, showEmail: true},
+		// a bad token on a public route is served anonymously
+		{token: "garbage", showEmail: false},
+	}
+
+	for _, i := range testCases {
+		req, err := http.NewRequest("GET", "/users", nil)
+		if err != nil {
+			t.Errorf("error occured: %v", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.Itoa(user.ID)})
+		if i.token != "" {
+			req.Header.Set("Authorization", "Bearer "+i.token)
+		}
+
+		rr := httptest.NewRecorder()
+		handler := middlewares.SetMiddlewareOptionalAuthentication(server.DB, server.GetUser)
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, http.StatusOK)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal(rr.Body.Bytes(), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert response to json: %v", err)
+		}
+
+		_, hasPassword := responseMap["password"]
+		assert.Equal(t, hasPassword, false)
+
+		_, hasEmail := responseMap["email"]
+		assert.Equal(t, hasEmail, i.showEmail)
+	}
+}
+
+func TestEmailChangeRequiresVerification(t *testing.T) {
+	for _, refresh := range []func() error{refreshUserTable, refreshSessionTables, refreshUserTokenTable} {
+		err := refresh()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	server.InitializeRoutes()
+
+	verifiedAt := time.Now()
+	user := models.User{
+		Firstname:  "Oscar",
+		Lastname:   "Martinez",
+		Email:      "oscar.martinez@dundermifflin.com",
+		Password:   "Accounting1",
+		VerifiedAt: &verifiedAt,
+	}
+	err := seedSingleUser(&user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := server.SignIn(server.DB, user.Email, "Accounting1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := fmt.Sprintf("/users/%d", user.ID)
+	update := `{"firstname": "Oscar", "lastname": "Martinez", "email": "%s", "password": "Accounting1"}`
+
+	// keeping the address keeps the verification
+	response := map[string]interface{}{}
+	serveJSON(t, "PUT", path, tokens.AccessToken, fmt.Sprintf(update, user.Email), http.StatusOK, &response)
+	assert.NotEqual(t, response["verified_at"], nil)
+
+	serveJSON(t, "PUT", path, tokens.AccessToken, fmt.Sprintf(update, "oscar@gmail.com"), http.StatusOK, &response)
+	assert.Equal(t, response["email"], "oscar@gmail.com")
+	assert.Equal(t, response["verified_at"], nil)
+
+	changed := models.User{}
+	_, err = changed.FindUserByID(server.DB, uint64(user.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, changed.IsVerified(), false)
+
+	message, found := outbox.Last("oscar@gmail.com")
+	assert.Equal(t, found, true)
+	if !tokenLinkPattern.MatchString(message.Body) {
+		t.Fatalf("no verification link in email: %q", message.Body)
+	}
+}